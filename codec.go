@@ -0,0 +1,129 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Codec reads and writes one config file format. Extensions returns the file
+// extensions handled by the codec, including the leading dot (e.g. ".yaml").
+// When several files are merged (see WithFiles), Unmarshal must also support
+// decoding into a *any, producing maps, slices and scalar values.
+type Codec interface {
+	Extensions() []string
+	Unmarshal(data []byte, v any) error
+	Marshal(v any) ([]byte, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+	// registeredCodecs holds the codecs available to every Provider.
+	registeredCodecs = codecSet{}.with(yamlCodec{}, jsonCodec{}, tomlCodec{})
+)
+
+// RegisterCodec makes c available to every Provider for its extensions,
+// replacing any codec previously registered for them, including the built-in
+// YAML (.yaml, .yml), JSON (.json) and TOML (.toml) codecs. It is typically called
+// from an init function. Panics if c is nil or has no valid extensions.
+func RegisterCodec(c Codec) {
+	validateCodec("RegisterCodec", c)
+
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	registeredCodecs = registeredCodecs.with(c)
+}
+
+// WithCodec makes c available to this Provider for its extensions, taking
+// precedence over codecs registered with RegisterCodec. Panics if c is nil or has
+// no valid extensions.
+func WithCodec[T any](c Codec) Option[T] {
+	return func(m *Provider[T]) {
+		validateCodec("WithCodec", c)
+		m.codecs = append(m.codecs, c)
+	}
+}
+
+func validateCodec(op string, c Codec) {
+	if c == nil {
+		panic("config: " + op + ": codec cannot be nil")
+	}
+	exts := c.Extensions()
+	if len(exts) == 0 {
+		panic("config: " + op + ": codec must have at least one extension")
+	}
+	for _, ext := range exts {
+		if len(ext) < 2 || ext[0] != '.' {
+			panic(fmt.Sprintf("config: %s: extension %q must start with a dot", op, ext))
+		}
+	}
+}
+
+// codecSet maps file extensions to codecs. It is treated as immutable.
+type codecSet map[string]Codec
+
+// registered returns the codecs registered with RegisterCodec.
+func registered() codecSet {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	return registeredCodecs
+}
+
+// with returns a copy of s extended with codecs, later ones taking precedence.
+func (s codecSet) with(codecs ...Codec) codecSet {
+	if len(codecs) == 0 {
+		return s
+	}
+	out := make(codecSet, len(s)+len(codecs))
+	for ext, c := range s {
+		out[ext] = c
+	}
+	for _, c := range codecs {
+		for _, ext := range c.Extensions() {
+			out[ext] = c
+		}
+	}
+	return out
+}
+
+// lookup returns the codec for ext, or an ErrUnsupportedConfigFileType error
+// listing the supported extensions.
+func (s codecSet) lookup(ext string) (Codec, error) {
+	if c, ok := s[ext]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("%w: %s (supported: %s)", ErrUnsupportedConfigFileType, ext, strings.Join(s.extensions(), ", "))
+}
+
+// extensions returns the sorted extensions with a codec.
+func (s codecSet) extensions() []string {
+	exts := make([]string, 0, len(s))
+	for ext := range s {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+type yamlCodec struct{}
+
+func (yamlCodec) Extensions() []string               { return []string{".yaml", ".yml"} }
+func (yamlCodec) Unmarshal(data []byte, v any) error { return yaml.Unmarshal(data, v) }
+func (yamlCodec) Marshal(v any) ([]byte, error)      { return yaml.Marshal(v) }
+
+type jsonCodec struct{}
+
+func (jsonCodec) Extensions() []string               { return []string{".json"} }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.MarshalIndent(v, "", "  ") }
+
+type tomlCodec struct{}
+
+func (tomlCodec) Extensions() []string               { return []string{".toml"} }
+func (tomlCodec) Unmarshal(data []byte, v any) error { return toml.Unmarshal(data, v) }
+func (tomlCodec) Marshal(v any) ([]byte, error)      { return toml.Marshal(v) }
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// kvCodec is a minimal key=value format, decoded through JSON so that it
+// honors json struct tags.
+type kvCodec struct{ ext string }
+
+func (c kvCodec) Extensions() []string { return []string{c.ext} }
+
+func (kvCodec) Unmarshal(data []byte, v any) error {
+	m := map[string]any{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		k, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("missing '=' in %q", line)
+		}
+		var parsed any
+		if json.Unmarshal([]byte(val), &parsed) != nil {
+			parsed = val
+		}
+		m[k] = parsed
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func (kvCodec) Marshal(v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&sb, "%s=%v\n", k, m[k])
+	}
+	return []byte(sb.String()), nil
+}
+
+func TestWithCodec(t *testing.T) {
+	td := t.TempDir()
+
+	t.Run("loads a file in a custom format", func(t *testing.T) {
+		path := filepath.Join(td, "config.kv")
+		writeFile(t, path, "name=custom\ncount=9\n")
+		t.Setenv("MYAPP_CONFIG_PATH", path)
+
+		cfg, _, _, err := New[testCfg2](
+			WithEnvPrefix[testCfg2]("MYAPP"),
+			WithCodec[testCfg2](kvCodec{ext: ".kv"}),
+		).Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if cfg.Name != "custom" || cfg.Count != 9 {
+			t.Fatalf("unexpected cfg: %+v", cfg)
+		}
+	})
+
+	t.Run("creates a persistent file in a custom format", func(t *testing.T) {
+		path := filepath.Join(td, "created", "config.kv")
+		t.Setenv("MYAPP_CONFIG_PATH", path)
+
+		_, _, created, err := New[testCfg2](
+			WithEnvPrefix[testCfg2]("MYAPP"),
+			WithPersistence[testCfg2]("myapp"),
+			WithDefaultFn[testCfg2](defFn),
+			WithCodec[testCfg2](kvCodec{ext: ".kv"}),
+		).Get()
+		if err != nil || !created {
+			t.Fatalf("Get: created=%v err=%v", created, err)
+		}
+		var got testCfg2
+		if err := (fileIO{codecs: codecSet{}.with(kvCodec{ext: ".kv"})}).loadFile(path, &got); err != nil {
+			t.Fatalf("read back: %v", err)
+		}
+		if got.Name != "default" || got.Count != 1 {
+			t.Fatalf("unexpected file contents: %+v", got)
+		}
+	})
+
+	t.Run("codec is not shared with other providers", func(t *testing.T) {
+		path := filepath.Join(td, "config.kv")
+		t.Setenv("MYAPP_CONFIG_PATH", path)
+
+		_, _, _, err := New[testCfg2](WithEnvPrefix[testCfg2]("MYAPP")).Get()
+		if !errors.Is(err, ErrUnsupportedConfigFileType) {
+			t.Fatalf("error = %v, want ErrUnsupportedConfigFileType", err)
+		}
+		if !strings.Contains(err.Error(), ".toml, .yaml, .yml") {
+			t.Fatalf("error does not list supported extensions: %v", err)
+		}
+	})
+}
+
+func TestRegisterCodec(t *testing.T) {
+	codecsMu.RLock()
+	saved := registeredCodecs
+	codecsMu.RUnlock()
+	t.Cleanup(func() {
+		codecsMu.Lock()
+		registeredCodecs = saved
+		codecsMu.Unlock()
+	})
+	RegisterCodec(kvCodec{ext: ".registered-kv"})
+
+	path := filepath.Join(t.TempDir(), "config.registered-kv")
+	writeFile(t, path, "name=global\n")
+	var got testCfg2
+	if err := loadFromFile(path, &got); err != nil {
+		t.Fatalf("loadFromFile: %v", err)
+	}
+	if got.Name != "global" {
+		t.Fatalf("Name = %q, want %q", got.Name, "global")
+	}
+}
+
+func TestCodecSet_Lookup(t *testing.T) {
+	s := codecSet{}.with(yamlCodec{}, jsonCodec{})
+	if c, err := s.lookup(".yml"); err != nil || c != (yamlCodec{}) {
+		t.Fatalf("lookup(.yml) = %v, %v", c, err)
+	}
+	_, err := s.lookup(".ini")
+	if !errors.Is(err, ErrUnsupportedConfigFileType) {
+		t.Fatalf("error = %v, want ErrUnsupportedConfigFileType", err)
+	}
+	if want := "unsupported config file type: .ini (supported: .json, .yaml, .yml)"; err.Error() != want {
+		t.Fatalf("error = %q, want %q", err.Error(), want)
+	}
+}
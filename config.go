@@ -1,12 +1,16 @@
 package config
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"reflect"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	modellib "github.com/ygrebnov/model"
 
@@ -14,53 +18,108 @@ import (
 )
 
 const (
-	configFileName = "config.yml"
-	envVarTagName  = "env"
+	configFileName      = "config.yml"
+	envVarTagName       = "env"
+	envSeparatorTagName = "envSeparator"
+	defaultEnvSeparator = ","
+
+	envKeyValueSeparatorTagName = "envKeyValueSeparator"
+	defaultEnvKeyValueSeparator = "="
 )
 
 // Exported error categories returned by this package. These are used with wrapping
 // so callers can detect error classes using errors.Is/As.
 //   - ErrEnsureConfigDir: failure to create parent directories for a config file.
-//   - ErrUnsupportedConfigFileType: file extension is neither .yaml/.yml nor .json.
-//   - ErrParse: failure to parse an existing config file.
+//   - ErrUnsupportedConfigFileType: no codec is registered for the file extension.
+//   - ErrParse: failure to parse an existing config file (see ParseError).
 //   - ErrFormat: failure to marshal a config to bytes (e.g., unsupported type).
 //   - ErrWrite: failure to write the config file to disk.
+//   - ErrNoConfigPath: Watch was called but no config file path could be resolved.
+//   - ErrEnv: an environment variable value could not be parsed into its field
+//     (see EnvError for details).
+//   - ErrSource: a custom Source registered with WithSources failed.
+//   - ErrUnknownField: a config file has a key without a matching field and
+//     WithStrict is set (see UnknownFieldError).
+//   - ErrInterpolation: a variable reference in a config file could not be expanded
+//     (see InterpolationError).
+//   - ErrInclude: an include directive could not be resolved, e.g. a missing file
+//     or an include cycle (see IncludeError).
 var (
 	ErrEnsureConfigDir           = errors.New("ensure config dir")
 	ErrUnsupportedConfigFileType = errors.New("unsupported config file type")
 	ErrParse                     = errors.New("parse config file")
 	ErrFormat                    = errors.New("format config")
 	ErrWrite                     = errors.New("write to config file")
+	ErrNoConfigPath              = errors.New("no config file path")
+	ErrEnv                       = errors.New("invalid environment override")
+	ErrSource                    = errors.New("load config source")
+	ErrUnknownField              = errors.New("unknown field")
+	ErrInterpolation             = errors.New("interpolate config file")
+	ErrInclude                   = errors.New("include config file")
 )
 
 // Provider manages the lifecycle of a configuration object of type T.
 //
-// A Provider[T] performs the following steps exactly once (it is safe to call Get
-// from multiple goroutines):
+// A Provider[T] performs the following steps once, on first use (it is safe to call
+// Get from multiple goroutines; see WithRetryOnError to retry after a failure and
+// Reload to run them again):
 //  1. Construct a new *T using the factory set via WithDefaultFn (or a zero-value fallback).
 //  2. If WithModel is set, bind a model.Model[T] to the same *T and call SetDefaults()
 //     to populate zero values using `default` struct tags.
-//  3. Resolve the configuration file path from either ${ENV_PREFIX}_CONFIG_PATH or
-//     a standard user config directory (if persistence is enabled with WithPersistence).
-//  4. Load overrides from the resolved file if it exists (or create it if persistent and missing).
+//  3. Resolve the configuration file path from the --config flag (WithConfigFlag),
+//     ${ENV_PREFIX}_CONFIG_PATH, WithConfigPath or a standard user config directory
+//     (if persistence is enabled with WithPersistence).
+//  4. Load overrides from the resolved file if it exists (or create it if persistent and missing),
+//     merged with the WithFiles and WithConfigDir layers and the WithProfile overlay.
 //  5. Apply environment overrides using `env` struct tags (or field name in SCREAMING_SNAKE_CASE).
+//     A <NAME>_FILE variable supplies the value of <NAME> from a file, e.g. a mounted secret.
+//     Unparsable values fail with ErrEnv unless WithLenientEnv is set.
+//     Steps 4 and 5 are the default Source chain, which WithSources can replace.
 //  6. If WithModel was set, validate the final object using model.Validate().
 //
-// Subsequent calls to Get() return the same pointer and metadata.
+// Subsequent calls to Get() return the same pointer and metadata until Reload
+// publishes a new value.
 type Provider[T any] struct {
-	mu          sync.RWMutex
-	initOnce    sync.Once
-	persist     bool
-	dirName     string
-	envPrefix   string
-	configPath  string
-	cfg         *T
-	defaultFn   func() *T
-	streams     streams.IOStreams
-	fileCreated bool
-	initErr     error
-	modelInit   ModelInit[T]
-	model       *modellib.Model[T]
+	mu           sync.RWMutex
+	initMu       sync.Mutex
+	initDone     atomic.Bool
+	reloadMu     sync.Mutex
+	retryOnErr   bool
+	lenientEnv   bool
+	strict       bool
+	interpolate  bool
+	includes     bool
+	upwardNames  []string
+	decoders     map[reflect.Type]func(string) (any, error)
+	dotEnv       []string
+	lookupEnv    func(string) (string, bool)
+	environ      func() []string
+	sources      []Source
+	codecs       []Codec
+	fsys         fs.FS
+	layerFiles   []string
+	configDirs   []string
+	sliceMerge   SliceMergePolicy
+	loadedFiles  []string
+	persist      bool
+	dirName      string
+	fileName     string
+	profile      string
+	envPrefix    string
+	configPath   string
+	explicitPath string
+	flagPath     *string
+	cfg          *T
+	defaultFn    func() *T
+	streams      streams.IOStreams
+	fileCreated  bool
+	initErr      error
+	modelInit    ModelInit[T]
+	model        *modellib.Model[T]
+
+	onChange      []func(old, new *T)
+	watchInterval time.Duration
+	watchDebounce time.Duration
 }
 
 // Option configures a Provider at construction time. Options are composable and
@@ -71,7 +130,11 @@ type Option[T any] func(*Provider[T])
 // If no WithDefaultFn is provided, New uses a zero-value factory that returns
 // a new *T with all fields zeroed.
 func New[T any](opts ...Option[T]) *Provider[T] {
-	p := &Provider[T]{}
+	p := &Provider[T]{
+		fileName:      configFileName,
+		watchInterval: defaultWatchInterval,
+		watchDebounce: defaultWatchDebounce,
+	}
 	for _, opt := range opts {
 		opt(p)
 	}
@@ -80,12 +143,20 @@ func New[T any](opts ...Option[T]) *Provider[T] {
 		// Must be a pointer to a struct for reflection logic
 		p.defaultFn = func() *T { var t T; return &t }
 	}
+	// Checked once all options are applied, so that WithCodec may come later.
+	codecs := registered().with(p.codecs...)
+	for _, name := range p.upwardNames {
+		if _, err := codecs.lookup(filepath.Ext(name)); err != nil && !extensionless(name) {
+			panic(fmt.Sprintf("config: WithUpwardSearch: %s: %v", name, err))
+		}
+	}
 
 	return p
 }
 
 // WithPersistence enables reading/writing the config file under a directory
-// named `dirName` inside the OS user config directory (e.g. XDG_CONFIG_HOME/<dirName>/config.yml).
+// named `dirName` inside the OS user config directory (e.g. XDG_CONFIG_HOME/<dirName>/config.yml;
+// see WithFileName to change the file name).
 // The provider will attempt to create the file with defaults when it does not exist.
 // Panics if dirName is empty.
 func WithPersistence[T any](dirName string) Option[T] {
@@ -98,9 +169,27 @@ func WithPersistence[T any](dirName string) Option[T] {
 	}
 }
 
+// WithFileName sets the name of the config file inside the WithPersistence
+// directory, "config.yml" by default. Its extension selects the codec used to
+// read the file and to create it, so "settings.json" creates a JSON file.
+// Panics if name is empty, contains a path separator or has no extension.
+func WithFileName[T any](name string) Option[T] {
+	return func(m *Provider[T]) {
+		switch {
+		case name == "":
+			panic("config: WithFileName: name cannot be empty")
+		case filepath.Base(name) != name:
+			panic("config: WithFileName: name cannot contain a path separator")
+		case filepath.Ext(name) == "":
+			panic("config: WithFileName: name must have an extension")
+		}
+		m.fileName = name
+	}
+}
+
 // WithEnvPrefix sets the prefix used for environment overrides, e.g. "MYAPP".
-// When set, Provider also honors ${PREFIX}_CONFIG_PATH as an absolute path to
-// the config file, which takes precedence over persistence.
+// When set, Provider also honors ${PREFIX}_CONFIG_PATH as the path to the config
+// file, which takes precedence over WithConfigPath and persistence.
 // Panics if prefix is empty.
 func WithEnvPrefix[T any](prefix string) Option[T] {
 	return func(m *Provider[T]) {
@@ -132,14 +221,54 @@ func WithStreams[T any](streams streams.IOStreams) Option[T] {
 	}
 }
 
+// WithRetryOnError makes Get retry initialization on the next call after a failure
+// instead of returning the cached error forever. This helps with transient startup
+// problems such as a config directory that is not mounted yet or a file that is
+// being written. Once initialization succeeds, the result is memoized as usual.
+func WithRetryOnError[T any]() Option[T] {
+	return func(m *Provider[T]) {
+		m.retryOnErr = true
+	}
+}
+
+// WithLenientEnv restores the legacy behavior of silently ignoring environment
+// variables whose values cannot be parsed into their fields (e.g. MYAPP_PORT=80a).
+// By default such values make Get fail with an error matching ErrEnv.
+func WithLenientEnv[T any]() Option[T] {
+	return func(m *Provider[T]) {
+		m.lenientEnv = true
+	}
+}
+
+// WithDecoder registers a function that parses environment variable values into
+// fields of type typ, for third-party types without an UnmarshalText method (e.g.
+// url.URL). fn may return a value of type typ or a pointer to one; a decoder for a
+// type also applies to pointers to it. Registered decoders take precedence over
+// UnmarshalText/UnmarshalJSON methods and built-in parsing. Panics if typ or fn is nil.
+func WithDecoder[T any](typ reflect.Type, fn func(string) (any, error)) Option[T] {
+	return func(m *Provider[T]) {
+		if typ == nil {
+			panic("config: WithDecoder: typ cannot be nil")
+		}
+		if fn == nil {
+			panic("config: WithDecoder: fn cannot be nil")
+		}
+		if m.decoders == nil {
+			m.decoders = map[reflect.Type]func(string) (any, error){}
+		}
+		m.decoders[typ] = fn
+	}
+}
+
 // ModelInit is a constructor hook that binds a model.Model[T] to the Provider-managed
 // *T. It allows the Provider to call SetDefaults() before file/env and Validate()
 // after file/env. Return the constructed model.Model[T] or an error.
 type ModelInit[T any] func(*T) (*modellib.Model[T], error)
 
 // WithModel enables integration with github.com/ygrebnov/model. The provided init
-// function is called exactly once during the first Get() to build a model.Model[T]
-// bound to the Provider's *T. The Provider will then:
+// function is called whenever the Provider builds a new *T (on the first Get, on
+// Reload and for the defaults of a created file) to bind a model.Model[T] to it.
+// The Provider will then:
 //   - call SetDefaults() before loading from file and env, and
 //   - call Validate() after all overrides are applied.
 //
@@ -155,105 +284,226 @@ func WithModel[T any](init ModelInit[T]) Option[T] {
 
 // Get initializes and returns the final configuration pointer, the resolved file
 // path (if any), whether the file was created on this run, and an error if initialization
-// failed. Get is safe for concurrent use; initialization runs at most once.
+// failed. Get is safe for concurrent use; initialization runs at most once, or until it
+// succeeds when WithRetryOnError is set.
+// After a successful Reload, Get returns the reloaded configuration.
+// path is the resolved config file only; Files lists every file that was merged.
 func (m *Provider[T]) Get() (cfg *T, path string, fileCreated bool, err error) {
-	m.initOnce.Do(func() {
-		// 1) Construct default config instance
-		m.cfg = m.defaultFn()
-
-		// 2) Optionally construct model wrapper around config instance
-		// to apply defaults before file/env operations.
-		if m.modelInit != nil {
-			mdl, err := m.modelInit(m.cfg)
-			if err != nil {
-				m.initErr = err
-				return
-			}
-			m.model = mdl
+	m.ensureInit(context.Background())
 
-			// Apply defaults before file/env, so they only fill zero values.
-			if err := m.model.SetDefaults(); err != nil {
-				m.initErr = err
-				return
-			}
-		}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.initErr != nil {
+		return nil, "", false, m.initErr
+	}
+	return m.cfg, m.configPath, m.fileCreated, nil
+}
 
-		// 3) Resolve config path. If this fails, abort initialization; otherwise continue
-		// into file operations and env overrides.
-		if err := m.resolveConfigPath(); err != nil {
-			m.initErr = err
-			return
-		}
+// Reload rebuilds the configuration from scratch by running the same steps as the
+// first Get (factory, model defaults, file, env, validation) on a fresh *T. The new
+// value is published only if every step succeeds; otherwise the previous value is
+// kept and the error is returned. Reload never mutates a published *T, so callers
+// holding a pointer from an earlier Get keep observing a consistent value.
+//
+// If the Provider has not been initialized yet, Reload performs the initialization
+// and returns its error. A successful Reload also clears a previously cached
+// initialization error. Concurrent Reload calls are serialized. After a new value
+// is published, callbacks registered with WithOnChange are invoked.
+func (m *Provider[T]) Reload(ctx context.Context) error {
+	if m.ensureInit(ctx) {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		return m.initErr
+	}
 
-		// 4) File operations
-		// Attempt to read from file if it exists. In persistent mode, create if missing.
-		e := loadFromFile(m.configPath, m.cfg)
-		switch {
-		case e != nil && !errors.Is(e, os.ErrNotExist):
-			m.initErr = e
+	m.reloadMu.Lock()
+	defer m.reloadMu.Unlock()
 
-		case e != nil && errors.Is(e, os.ErrNotExist) && m.persist:
-			if pe := EnsurePath(m.configPath); pe != nil {
-				m.initErr = errors.Join(ErrEnsureConfigDir, pe)
-				return
-			}
+	s, err := m.load(ctx)
+	if err != nil {
+		return err
+	}
 
-			if we := writeToFile(m.configPath, m.cfg); we != nil {
-				m.initErr = errors.Join(ErrWrite, we)
-				return
-			}
-			m.fileCreated = true
-			if m.streams != nil && m.streams.Out() != nil {
-				fmt.Fprintf(m.streams.Out(), "config: created new config at %s\n", m.configPath)
-			}
-		case e == nil && m.persist:
-			if m.streams != nil && m.streams.Out() != nil {
-				fmt.Fprintf(m.streams.Out(), "config: loaded from %s\n", m.configPath)
-			}
-		}
+	m.mu.Lock()
+	old := m.cfg
+	m.initErr = nil
+	m.publish(s)
+	m.mu.Unlock()
 
-		// 5) Apply environment overrides
-		m.loadFromEnv(m.cfg)
+	// Callbacks run outside of m.mu so they may call Get, but still under
+	// reloadMu so they observe changes in order.
+	for _, fn := range m.onChange {
+		fn(old, s.cfg)
+	}
+	return nil
+}
 
-		// 6) Optionally apply model validation after file/env operations.
-		if m.model != nil {
-			if err := m.model.Validate(); err != nil {
-				m.initErr = err
-				return
+// snapshot is the outcome of one successful run of the load pipeline.
+type snapshot[T any] struct {
+	cfg         *T
+	model       *modellib.Model[T]
+	path        string
+	files       []string
+	fileCreated bool
+}
+
+// ensureInit runs the initial load unless it already completed, and reports
+// whether this call performed it. A failed initialization counts as completed
+// unless WithRetryOnError is set.
+func (m *Provider[T]) ensureInit(ctx context.Context) bool {
+	if m.initDone.Load() {
+		return false
+	}
+	m.initMu.Lock()
+	defer m.initMu.Unlock()
+	if m.initDone.Load() {
+		return false
+	}
+	if err := m.init(ctx); err == nil || !m.retryOnErr {
+		m.initDone.Store(true)
+	}
+	return true
+}
+
+// init runs the load pipeline for the first time and publishes the result or
+// caches the error. It must only be called from ensureInit.
+func (m *Provider[T]) init(ctx context.Context) error {
+	s, err := m.load(ctx)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.initErr = err
+		return err
+	}
+	m.initErr = nil
+	m.publish(s)
+	return nil
+}
+
+// publish makes s the current configuration. The caller must hold m.mu for writing.
+func (m *Provider[T]) publish(s snapshot[T]) {
+	m.cfg = s.cfg
+	m.model = s.model
+	m.configPath = s.path
+	m.loadedFiles = s.files
+	// fileCreated reports whether the file was created during the Provider lifetime.
+	m.fileCreated = m.fileCreated || s.fileCreated
+}
+
+// load builds a new configuration value without touching the published state.
+func (m *Provider[T]) load(ctx context.Context) (snapshot[T], error) {
+	var s snapshot[T]
+
+	// 1) + 2) Construct the default config instance and apply the model defaults.
+	cfg, mdl, err := m.newConfig()
+	if err != nil {
+		return s, err
+	}
+	s.cfg, s.model = cfg, mdl
+
+	if err := ctx.Err(); err != nil {
+		return s, err
+	}
+
+	// 3) Resolve config path. If this fails, abort; otherwise continue
+	// into file operations and env overrides.
+	path, err := m.resolveConfigPath()
+	if err != nil {
+		return s, err
+	}
+	s.path = path
+	fio := m.fileIO()
+	project, err := m.projectFiles(fio)
+	if err != nil {
+		return s, err
+	}
+	fio.projects = project
+	profile, err := m.profileName()
+	if err != nil {
+		return s, err
+	}
+
+	// 4) + 5) Apply sources in order: by default the config file (created from the
+	// defaults if missing and persistent), then environment overrides.
+	file := &fileSource{
+		defaults: func() (any, error) {
+			cfg, _, err := m.newConfig()
+			return cfg, err
+		},
+		fio:      fio,
+		layers:   m.fileLayers(),
+		dirs:     m.configDirs,
+		path:     path,
+		overlays: append([]string{profilePath(path, profile)}, project...),
+		policy:   m.sliceMerge,
+		create:   m.persist,
+		streams:  m.streams,
+	}
+	for _, src := range m.sourceChain(file) {
+		if err := ctx.Err(); err != nil {
+			return s, err
+		}
+		if err := src.Load(ctx, s.cfg); err != nil {
+			switch src.(type) {
+			case *fileSource, *envSource:
+				return s, err
 			}
+			return s, fmt.Errorf("%w %s: %w", ErrSource, src.Name(), err)
 		}
-	})
+	}
+	s.files = file.loaded
+	s.fileCreated = file.created
 
-	// After once: return cached state or error
-	if m.initErr != nil {
-		return nil, "", false, m.initErr
+	// 6) Optionally apply model validation after file/env operations.
+	if s.model != nil {
+		if err := s.model.Validate(); err != nil {
+			return s, err
+		}
 	}
 
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	return m.cfg, m.configPath, m.fileCreated, nil
+	return s, nil
 }
 
-func (m *Provider[T]) resolveConfigPath() error {
-	if m.envPrefix != "" {
-		if configPath := os.Getenv(m.envPrefix + "_CONFIG_PATH"); configPath != "" {
-			m.configPath = configPath
-			return nil
-		}
+// newConfig returns a new config instance holding the defaults: the value of the
+// default factory with the model defaults applied, if WithModel is set, along
+// with its model.
+func (m *Provider[T]) newConfig() (*T, *modellib.Model[T], error) {
+	cfg := m.defaultFn()
+	if m.modelInit == nil {
+		return cfg, nil, nil
+	}
+	mdl, err := m.modelInit(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Apply defaults before file/env, so they only fill zero values.
+	if err := mdl.SetDefaults(); err != nil {
+		return nil, nil, err
+	}
+	return cfg, mdl, nil
+}
+
+// resolveConfigPath returns the config file path from the --config flag,
+// ${PREFIX}_CONFIG_PATH, WithConfigPath or the persistence directory. An empty
+// path means no file I/O is performed.
+func (m *Provider[T]) resolveConfigPath() (string, error) {
+	if configPath := m.explicitConfigPath(); configPath != "" {
+		return m.absConfigPath(configPath)
 	}
 	if m.dirName == "" {
 		// Non-persistent mode.
-		return nil
+		return "", nil
 	}
 	// Prefer XDG_CONFIG_HOME explicitly when set, then fall back to os.UserConfigDir.
-	userConfigDir := os.Getenv("XDG_CONFIG_HOME")
+	userConfigDir := m.getenv("XDG_CONFIG_HOME")
 	if userConfigDir == "" {
 		var err error
 		userConfigDir, err = os.UserConfigDir()
 		if err != nil {
 			// Critical when persistent; otherwise emit a note to streams if available.
 			if m.persist {
-				return fmt.Errorf("cannot determine user config dir: %w", err)
+				return "", fmt.Errorf("cannot determine user config dir: %w", err)
 			}
 			if m.streams != nil && m.streams.ErrOut() != nil {
 				fmt.Fprintf(
@@ -263,17 +513,59 @@ func (m *Provider[T]) resolveConfigPath() error {
 				)
 			}
 			// Non-persistent: continue without setting a path.
-			return nil
+			return "", nil
 		}
 	}
-	m.configPath = filepath.Join(userConfigDir, m.dirName, configFileName)
-	return nil
+	return filepath.Join(userConfigDir, m.dirName, m.fileName), nil
 }
 
-func (m *Provider[T]) loadFromEnv(cfg *T) {
-	rv := reflect.ValueOf(cfg)
-	if rv.Kind() != reflect.Pointer || rv.IsNil() {
-		return
+// loadFromEnv applies environment overrides to cfg. Values that cannot be parsed
+// are returned as *EnvError values joined together, unless WithLenientEnv is set.
+func (m *Provider[T]) loadFromEnv(cfg *T) error {
+	return m.envSource().Load(context.Background(), cfg)
+}
+
+// fileIO returns the file settings of the Provider.
+func (m *Provider[T]) fileIO() fileIO {
+	return fileIO{
+		fsys:        m.fsys,
+		codecs:      registered().with(m.codecs...),
+		strict:      m.strict,
+		interpolate: m.interpolate,
+		includes:    m.includes,
+		rcNames:     m.upwardNames,
+		lookupEnv:   m.envLookup(),
+	}
+}
+
+// envSource returns the Provider's built-in environment override source.
+func (m *Provider[T]) envSource() *envSource {
+	return &envSource{
+		prefix:   m.envPrefix,
+		lenient:  m.lenientEnv,
+		decoders: m.decoders,
+		dotEnv:   m.dotEnv,
+		fio:      m.fileIO(),
+		env:      m.env,
+	}
+}
+
+// sourceChain returns the sources to apply in order, with the placeholders from
+// ProviderFileSource and ProviderEnvSource replaced by the built-in sources.
+func (m *Provider[T]) sourceChain(file *fileSource) []Source {
+	if m.sources == nil {
+		return []Source{file, m.envSource()}
+	}
+	chain := make([]Source, len(m.sources))
+	for i, src := range m.sources {
+		switch src {
+		case providerFile:
+			chain[i] = file
+		case providerEnv:
+			chain[i] = m.envSource()
+		default:
+			chain[i] = src
+		}
 	}
-	applyEnv(rv.Elem(), m.envPrefix, nil)
+	return chain
 }
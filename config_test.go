@@ -3,6 +3,8 @@ package config
 import (
 	"bytes"
 	"io"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -404,4 +406,221 @@ func TestNew_Panics(t *testing.T) {
 		}()
 		_ = New[testCfg](WithModel[testCfg](nil))
 	})
+
+	t.Run("WithDecoder nil type panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithDecoder[testCfg](nil, func(string) (any, error) { return nil, nil }))
+	})
+
+	t.Run("WithDecoder nil fn panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithDecoder[testCfg](reflect.TypeOf(""), nil))
+	})
+
+	t.Run("WithSources nil source panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithSources[testCfg](EnvSource("APP"), nil))
+	})
+
+	t.Run("WithFiles empty path panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithFiles[testCfg]("a.yml", ""))
+	})
+
+	t.Run("WithFS nil panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithFS[testCfg](nil))
+	})
+
+	t.Run("WithUpwardSearch without names panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithUpwardSearch[testCfg]())
+	})
+
+	t.Run("WithUpwardSearch without a codec panics", func(t *testing.T) {
+		for _, name := range []string{".myapprc.ini", "myapp.ini"} {
+			func() {
+				defer func() {
+					if r := recover(); r == nil {
+						t.Fatalf("WithUpwardSearch(%q): expected panic, got none", name)
+					}
+				}()
+				_ = New[testCfg](WithUpwardSearch[testCfg](name))
+			}()
+		}
+		// A codec added by a later option is taken into account.
+		_ = New[testCfg](WithUpwardSearch[testCfg](".myapprc.ini"), WithCodec[testCfg](kvCodec{ext: ".ini"}))
+	})
+
+	t.Run("WithUpwardSearch with path separator panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithUpwardSearch[testCfg](filepath.Join("a", "b.yml")))
+	})
+
+	t.Run("WithProfile invalid name panics", func(t *testing.T) {
+		for _, name := range []string{filepath.Join("..", "x"), ".."} {
+			func() {
+				defer func() {
+					if r := recover(); r == nil {
+						t.Fatalf("WithProfile(%q): expected panic, got none", name)
+					}
+				}()
+				_ = New[testCfg](WithProfile[testCfg](name))
+			}()
+		}
+	})
+
+	t.Run("WithConfigPath empty panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithConfigPath[testCfg](""))
+	})
+
+	t.Run("WithConfigFlag nil panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithConfigFlag[testCfg](nil))
+	})
+
+	t.Run("WithDotEnv empty path panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithDotEnv[testCfg](".env", ""))
+	})
+
+	t.Run("WithLookupEnv nil panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithLookupEnv[testCfg](nil))
+	})
+
+	t.Run("WithEnviron nil panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithEnviron[testCfg](nil))
+	})
+
+	t.Run("WithProfile empty panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithProfile[testCfg](""))
+	})
+
+	t.Run("WithProfile with path separator panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithProfile[testCfg](filepath.Join("a", "b")))
+	})
+
+	t.Run("WithConfigDir empty panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithConfigDir[testCfg](""))
+	})
+
+	t.Run("WithSliceMerge unknown policy panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithSliceMerge[testCfg](SliceMergePolicy(42)))
+	})
+
+	t.Run("WithCodec nil panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithCodec[testCfg](nil))
+	})
+
+	t.Run("WithCodec extension without dot panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithCodec[testCfg](kvCodec{ext: "kv"}))
+	})
+
+	t.Run("WithFileName empty panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithFileName[testCfg](""))
+	})
+
+	t.Run("WithFileName with path separator panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithFileName[testCfg](filepath.Join("dir", "config.yml")))
+	})
+
+	t.Run("WithFileName without extension panics", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg](WithFileName[testCfg]("config"))
+	})
 }
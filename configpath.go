@@ -0,0 +1,73 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+)
+
+// ConfigFlagName is the name of the flag registered by WithConfigFlag.
+const ConfigFlagName = "config"
+
+// WithConfigPath sets the config file path explicitly. The config path is
+// resolved with the following precedence:
+//
+//  1. the --config flag registered with WithConfigFlag, if set;
+//  2. ${PREFIX}_CONFIG_PATH, if WithEnvPrefix is set;
+//  3. the path set with WithConfigPath;
+//  4. the WithPersistence path in the user config directory.
+//
+// Relative paths are resolved against the working directory when the config is
+// loaded. The file is created if it is missing and WithPersistence is set.
+// Panics if path is empty.
+func WithConfigPath[T any](path string) Option[T] {
+	return func(m *Provider[T]) {
+		if path == "" {
+			panic("config: WithConfigPath: path cannot be empty")
+		}
+		m.explicitPath = path
+	}
+}
+
+// WithConfigFlag registers a --config flag on fs that sets the config file path,
+// taking precedence over the other ways to set it (see WithConfigPath). The flag
+// is read when the config is first loaded, so fs must be parsed before Get or
+// Reload is called. Panics if fs is nil or already defines the flag.
+func WithConfigFlag[T any](fs *flag.FlagSet) Option[T] {
+	return func(m *Provider[T]) {
+		if fs == nil {
+			panic("config: WithConfigFlag: fs cannot be nil")
+		}
+		if fs.Lookup(ConfigFlagName) != nil {
+			panic("config: WithConfigFlag: flag --" + ConfigFlagName + " is already defined")
+		}
+		m.flagPath = fs.String(ConfigFlagName, "", "path to the config file")
+	}
+}
+
+// explicitConfigPath returns the config path set by the --config flag,
+// ${PREFIX}_CONFIG_PATH or WithConfigPath, in order of precedence, if any.
+func (m *Provider[T]) explicitConfigPath() string {
+	if m.flagPath != nil && *m.flagPath != "" {
+		return *m.flagPath
+	}
+	if m.envPrefix != "" {
+		if p := m.getenv(m.envPrefix + "_CONFIG_PATH"); p != "" {
+			return p
+		}
+	}
+	return m.explicitPath
+}
+
+// absConfigPath resolves a relative config path against the working directory.
+// Paths in a file system set with WithFS are kept as fs.FS names.
+func (m *Provider[T]) absConfigPath(p string) (string, error) {
+	if m.fsys != nil || filepath.IsAbs(p) {
+		return p, nil
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", fmt.Errorf("resolve config path %s: %w", p, err)
+	}
+	return abs, nil
+}
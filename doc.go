@@ -2,7 +2,7 @@
 //
 // It supports:
 //  1. Constructing a config instance via a user-provided default factory.
-//  2. Loading overrides from YAML/JSON files (optionally persisted under a user
+//  2. Loading overrides from YAML/JSON/TOML files (optionally persisted under a user
 //     config directory).
 //  3. Applying environment variable overrides using `env` tags or field names
 //     converted to SCREAMING_SNAKE_CASE.
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// defaultDotEnvFile is the dotenv file loaded by WithDotEnv without paths.
+const defaultDotEnvFile = ".env"
+
+// WithDotEnv loads variables from dotenv files (".env" if no paths are given) for
+// the environment override step. The process environment is not modified, and
+// its variables take precedence over the files; among the files, later ones take
+// precedence. Missing files are skipped. The supported syntax is:
+//
+//	# comment
+//	export NAME=value           # "export" is optional; inline comments follow a space
+//	NAME='literal $value'       # no escapes, may span lines
+//	NAME="line 1\nline 2"       # \n, \r, \t, \", \\ and \$ escapes, may span lines
+//
+// A malformed file is reported as a *ParseError. Panics if a path is empty.
+func WithDotEnv[T any](paths ...string) Option[T] {
+	return func(m *Provider[T]) {
+		if len(paths) == 0 {
+			paths = []string{defaultDotEnvFile}
+		}
+		for _, p := range paths {
+			if p == "" {
+				panic("config: WithDotEnv: path cannot be empty")
+			}
+		}
+		m.dotEnv = append(m.dotEnv, paths...)
+	}
+}
+
+// dotEnvVars returns the variables of the given dotenv files, later files taking
+// precedence, overridden by env.
+func (f fileIO) dotEnvVars(paths []string, env envVars) (envVars, error) {
+	vars := map[string]string{}
+	for _, p := range paths {
+		data, err := f.readFile(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return envVars{}, fmt.Errorf("read %s: %w", p, err)
+		}
+		parsed, err := parseDotEnv(data)
+		if err != nil {
+			return envVars{}, newParseError(p, data, err)
+		}
+		for name, value := range parsed {
+			vars[name] = value
+		}
+	}
+	for name, value := range env.vars {
+		vars[name] = value
+	}
+	return envVars{lookup: env.lookup, vars: vars}, nil
+}
+
+// parseDotEnv parses dotenv data. Errors are returned as a *ParseError with the
+// position of the problem.
+func parseDotEnv(data []byte) (map[string]string, error) {
+	p := &dotEnvParser{data: data, src: string(data)}
+	vars := map[string]string{}
+	for {
+		p.skipBlank()
+		if p.off == len(p.src) {
+			return vars, nil
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		vars[name] = value
+	}
+}
+
+// dotEnvParser scans dotenv data.
+type dotEnvParser struct {
+	data []byte
+	src  string
+	off  int
+}
+
+func (p *dotEnvParser) errorf(off int, format string, args ...any) error {
+	line, col := lineCol(p.data, int64(off))
+	return &ParseError{Line: line, Column: col, Err: fmt.Errorf(format, args...)}
+}
+
+func (p *dotEnvParser) skipSpaces() {
+	for p.off < len(p.src) && (p.src[p.off] == ' ' || p.src[p.off] == '\t') {
+		p.off++
+	}
+}
+
+// skipBlank skips whitespace, line breaks and comment lines.
+func (p *dotEnvParser) skipBlank() {
+	for p.off < len(p.src) {
+		switch p.src[p.off] {
+		case ' ', '\t', '\r', '\n':
+			p.off++
+		case '#':
+			p.skipLine()
+		default:
+			return
+		}
+	}
+}
+
+// skipLine moves past the end of the current line.
+func (p *dotEnvParser) skipLine() {
+	if i := strings.IndexByte(p.src[p.off:], '\n'); i >= 0 {
+		p.off += i + 1
+	} else {
+		p.off = len(p.src)
+	}
+}
+
+// name scans an optional "export" keyword, a variable name and the "=".
+func (p *dotEnvParser) name() (string, error) {
+	if rest := p.src[p.off:]; strings.HasPrefix(rest, "export") && len(rest) > 6 && (rest[6] == ' ' || rest[6] == '\t') {
+		p.off += 6
+		p.skipSpaces()
+	}
+	start := p.off
+	for p.off < len(p.src) && isDotEnvNameByte(p.src[p.off], p.off == start) {
+		p.off++
+	}
+	if p.off == start {
+		return "", p.errorf(start, "invalid variable name")
+	}
+	name := p.src[start:p.off]
+	p.skipSpaces()
+	if p.off == len(p.src) || p.src[p.off] != '=' {
+		return "", p.errorf(p.off, "expected '=' after %s", name)
+	}
+	p.off++
+	p.skipSpaces()
+	return name, nil
+}
+
+func isDotEnvNameByte(c byte, first bool) bool {
+	switch {
+	case c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+		return true
+	case '0' <= c && c <= '9' || c == '.':
+		return !first
+	}
+	return false
+}
+
+// value scans a quoted or unquoted value up to the end of its line.
+func (p *dotEnvParser) value() (string, error) {
+	if p.off == len(p.src) {
+		return "", nil
+	}
+	start := p.off
+	switch p.src[p.off] {
+	case '\'':
+		end := strings.IndexByte(p.src[p.off+1:], '\'')
+		if end < 0 {
+			return "", p.errorf(start, "unterminated single-quoted value")
+		}
+		value := p.src[p.off+1 : p.off+1+end]
+		p.off += end + 2
+		return value, p.endOfLine()
+	case '"':
+		var b strings.Builder
+		for p.off++; p.off < len(p.src); p.off++ {
+			c := p.src[p.off]
+			switch {
+			case c == '"':
+				p.off++
+				return b.String(), p.endOfLine()
+			case c == '\\' && p.off+1 < len(p.src):
+				p.off++
+				switch e := p.src[p.off]; e {
+				case 'n':
+					b.WriteByte('\n')
+				case 'r':
+					b.WriteByte('\r')
+				case 't':
+					b.WriteByte('\t')
+				case '"', '\\', '$':
+					b.WriteByte(e)
+				default:
+					b.WriteByte('\\')
+					b.WriteByte(e)
+				}
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", p.errorf(start, "unterminated double-quoted value")
+	}
+
+	end := strings.IndexByte(p.src[p.off:], '\n')
+	if end < 0 {
+		end = len(p.src) - p.off
+	}
+	value := p.src[p.off : p.off+end]
+	p.off += end
+	for i := 0; i < len(value); i++ {
+		if value[i] == '#' && (i == 0 || value[i-1] == ' ' || value[i-1] == '\t') {
+			value = value[:i]
+			break
+		}
+	}
+	return strings.TrimRight(value, " \t\r"), nil
+}
+
+// endOfLine checks that only blanks or a comment follow a quoted value.
+func (p *dotEnvParser) endOfLine() error {
+	p.skipSpaces()
+	switch {
+	case p.off == len(p.src):
+		return nil
+	case p.src[p.off] == '#' || p.src[p.off] == '\n' || p.src[p.off] == '\r':
+		p.skipLine()
+		return nil
+	}
+	return p.errorf(p.off, "unexpected %q after quoted value", p.src[p.off])
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	data := strings.Join([]string{
+		"# comment",
+		"",
+		"PLAIN=value",
+		"  SPACED = padded value  ",
+		"export EXPORTED=yes",
+		"export=not a keyword",
+		"EMPTY=",
+		"INLINE=value # comment",
+		"HASH=a#b",
+		`SINGLE='it is $literal \n'  # comment`,
+		`DOUBLE="tab\there \"quoted\" \$HOME \\ \q"`,
+		"MULTI=\"line 1",
+		"line 2\"",
+		"RAW='a",
+		"b'",
+		"CRLF=value\r",
+		"dotted.name=1",
+	}, "\n")
+	got, err := parseDotEnv([]byte(data))
+	if err != nil {
+		t.Fatalf("parseDotEnv: %v", err)
+	}
+	want := map[string]string{
+		"PLAIN":       "value",
+		"SPACED":      "padded value",
+		"EXPORTED":    "yes",
+		"export":      "not a keyword",
+		"EMPTY":       "",
+		"INLINE":      "value",
+		"HASH":        "a#b",
+		"SINGLE":      `it is $literal \n`,
+		"DOUBLE":      "tab\there \"quoted\" $HOME \\ \\q",
+		"MULTI":       "line 1\nline 2",
+		"RAW":         "a\nb",
+		"CRLF":        "value",
+		"dotted.name": "1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseDotEnv =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestParseDotEnv_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		line, col int
+		msg       string
+	}{
+		{name: "missing equals", data: "A=1\nNAME value\n", line: 2, col: 6, msg: "expected '=' after NAME"},
+		{name: "invalid name", data: "1A=1\n", line: 1, col: 1, msg: "invalid variable name"},
+		{name: "unterminated double quote", data: "A=\"abc\n", line: 1, col: 3, msg: "unterminated double-quoted value"},
+		{name: "unterminated single quote", data: "A=1\nB='abc", line: 2, col: 3, msg: "unterminated single-quoted value"},
+		{name: "text after quote", data: "A=\"abc\"def\n", line: 1, col: 8, msg: `unexpected 'd' after quoted value`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseDotEnv([]byte(tc.data))
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("err = %v, want *ParseError", err)
+			}
+			if pe.Line != tc.line || pe.Column != tc.col || pe.Err.Error() != tc.msg {
+				t.Fatalf("got %d:%d %q, want %d:%d %q", pe.Line, pe.Column, pe.Err, tc.line, tc.col, tc.msg)
+			}
+		})
+	}
+}
+
+func TestProvider_Get_DotEnv(t *testing.T) {
+	td := t.TempDir()
+	base := filepath.Join(td, ".env")
+	local := filepath.Join(td, ".env.local")
+	writeFile(t, base, "MYAPP_NAME=dotenv\nMYAPP_PORT=80\nMYAPP_HOSTS=\"a,b\"\nMYAPP_DB_HOST=db.local\n")
+	writeFile(t, local, "export MYAPP_PORT=8080\n")
+
+	t.Run("real env wins, later files win", func(t *testing.T) {
+		t.Setenv("MYAPP_NAME", "real")
+		p := New[filesCfg](
+			WithEnvPrefix[filesCfg]("MYAPP"),
+			WithDotEnv[filesCfg](base, filepath.Join(td, "missing.env"), local),
+		)
+		cfg, _, _, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if cfg.Name != "real" || cfg.Port != 8080 || cfg.DB.Host != "db.local" || !reflect.DeepEqual(cfg.Hosts, []string{"a", "b"}) {
+			t.Fatalf("cfg = %+v", cfg)
+		}
+		if _, ok := os.LookupEnv("MYAPP_PORT"); ok {
+			t.Fatalf("process environment modified")
+		}
+	})
+
+	t.Run("default .env in the working directory", func(t *testing.T) {
+		chdir(t, td)
+		p := New[filesCfg](WithEnvPrefix[filesCfg]("MYAPP"), WithDotEnv[filesCfg]())
+		cfg, _, _, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if cfg.Name != "dotenv" || cfg.Port != 80 {
+			t.Fatalf("cfg = %+v", cfg)
+		}
+	})
+
+	t.Run("malformed file", func(t *testing.T) {
+		bad := filepath.Join(td, "bad.env")
+		writeFile(t, bad, "MYAPP_NAME=\"unterminated\n")
+		p := New[filesCfg](WithEnvPrefix[filesCfg]("MYAPP"), WithDotEnv[filesCfg](bad))
+		_, _, _, err := p.Get()
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Path != bad || pe.Line != 1 || pe.Snippet == "" {
+			t.Fatalf("err = %v, want *ParseError for %s:1", err, bad)
+		}
+	})
+}
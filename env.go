@@ -0,0 +1,612 @@
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// envFileSuffix marks a variable naming a file to read the value from, e.g.
+// MYAPP_DB_PASSWORD_FILE=/run/secrets/db_password for MYAPP_DB_PASSWORD.
+const envFileSuffix = "_FILE"
+
+// EnvError describes an environment variable whose value could not be applied
+// to the config field it maps to. It matches ErrEnv with errors.Is.
+type EnvError struct {
+	Var   string       // environment variable name, e.g. "MYAPP_PORT"
+	Field string       // Go field path, e.g. "Server.Port"
+	Value string       // raw value of the variable
+	Type  reflect.Type // type of the target field
+	Err   error        // underlying parse error
+}
+
+func (e *EnvError) Error() string {
+	return fmt.Sprintf("%s: %s=%q: cannot set %s (%s): %v", ErrEnv, e.Var, e.Value, e.Field, e.Type, e.Err)
+}
+
+func (e *EnvError) Unwrap() []error { return []error{ErrEnv, e.Err} }
+
+// envLoader applies environment overrides onto a struct and collects the
+// errors of variables that could not be applied.
+type envLoader struct {
+	env      envVars
+	prefix   string
+	decoders map[reflect.Type]func(string) (any, error)
+	errs     []error
+}
+
+// applyEnv walks the fields of v (a struct or a pointer to one). segments are the
+// env name segments and fields the Go field names leading to v.
+func (l *envLoader) applyEnv(v reflect.Value, segments, fields []string) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	t := v.Type()
+	siblings := map[string]bool{}
+	for i := 0; i < v.NumField(); i++ {
+		if sf := t.Field(i); sf.PkgPath == "" {
+			if seg, ok := envSegment(sf); ok {
+				siblings[seg] = true
+			}
+		}
+	}
+	for i := 0; i < v.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		seg, ok := envSegment(sf)
+		if !ok {
+			continue
+		}
+		field := v.Field(i)
+		segs := append(segments[:len(segments):len(segments)], seg)
+		path := append(fields[:len(fields):len(fields)], sf.Name)
+		envName := buildEnvName(l.prefix, segs)
+		// A sibling field named <SEG>_FILE keeps its own variable.
+		fileName := envName + envFileSuffix
+		if siblings[seg+envFileSuffix] {
+			fileName = ""
+		}
+		switch {
+		case l.isScalar(field.Type()):
+			if field.CanSet() {
+				l.apply(envName, fileName, path, field.Type(), func(raw string) error {
+					return l.setScalar(field, raw)
+				})
+			}
+		case field.Kind() == reflect.Struct:
+			l.applyEnv(field, segs, path)
+		case field.Kind() == reflect.Pointer && field.Type().Elem().Kind() == reflect.Struct:
+			// Allocate *struct only if there is at least one nested env var present
+			// for this segment (e.g., APP_PINNER_*). This avoids allocating when no
+			// relevant env vars are set.
+			if l.env.hasAnyWithPrefix(envName + "_") {
+				if field.IsNil() && field.CanSet() {
+					field.Set(reflect.New(field.Type().Elem()))
+				}
+				l.applyEnv(field, segs, path)
+			}
+		case isList(field.Type()) && l.isScalar(field.Type().Elem()):
+			if field.CanSet() {
+				sep := sf.Tag.Get(envSeparatorTagName)
+				if sep == "" {
+					sep = defaultEnvSeparator
+				}
+				l.apply(envName, fileName, path, field.Type(), func(raw string) error {
+					return l.setList(field, raw, sep)
+				})
+			}
+		case isList(field.Type()) && isStruct(field.Type().Elem()):
+			l.applyIndexed(field, envName, segs, path)
+		case field.Kind() == reflect.Map && l.isScalar(field.Type().Key()):
+			l.applyMap(field, sf, envName, fileName, segs, path)
+		}
+	}
+}
+
+// apply passes the value of the variable name to set. If fileName (name with the
+// _FILE suffix, or "" to disable it) is set instead, the content of the file it
+// names, without a trailing newline, is passed. Setting both is an error. Errors
+// are recorded against the variable actually set; the content of a file is not
+// included in them, as it is usually a secret.
+func (l *envLoader) apply(name, fileName string, path []string, typ reflect.Type, set func(raw string) error) {
+	raw, ok := l.env.getString(name)
+	file, fromFile := "", false
+	if fileName != "" {
+		file, fromFile = l.env.getString(fileName)
+	}
+	switch {
+	case !fromFile:
+		if ok {
+			if err := set(raw); err != nil {
+				l.fail(name, path, raw, typ, err)
+			}
+		}
+		return
+	case ok:
+		l.fail(fileName, path, file, typ, fmt.Errorf("%s is also set", name))
+		return
+	}
+	// Secrets are mounted on the host, not in a WithFS file system.
+	data, err := os.ReadFile(file)
+	if err == nil {
+		err = set(trimNewline(string(data)))
+	}
+	if err != nil {
+		l.fail(fileName, path, file, typ, err)
+	}
+}
+
+// trimNewline removes a single trailing "\n" or "\r\n" from s.
+func trimNewline(s string) string {
+	s, ok := strings.CutSuffix(s, "\n")
+	if ok {
+		s = strings.TrimSuffix(s, "\r")
+	}
+	return s
+}
+
+// applyMap applies map entries from the inline form NAME=k1=v1,k2=v2 or fileName
+// (scalar values only) and then from every NAME_<KEY> variable, or
+// NAME_<KEY>_<FIELD> for struct values. Discovered keys reuse an existing map key
+// that matches case insensitively and are lowercased otherwise. The map is
+// allocated on demand.
+func (l *envLoader) applyMap(v reflect.Value, sf reflect.StructField, name, fileName string, segments, fields []string) {
+	t := v.Type()
+	if !v.CanSet() || (!l.isScalar(t.Elem()) && !isStruct(t.Elem())) {
+		return
+	}
+	last := fields[len(fields)-1]
+	entryPath := func(key string) []string {
+		return append(fields[:len(fields)-1:len(fields)-1], last+"["+key+"]")
+	}
+
+	if l.isScalar(t.Elem()) {
+		sep := sf.Tag.Get(envSeparatorTagName)
+		if sep == "" {
+			sep = defaultEnvSeparator
+		}
+		kvSep := sf.Tag.Get(envKeyValueSeparatorTagName)
+		if kvSep == "" {
+			kvSep = defaultEnvKeyValueSeparator
+		}
+		l.apply(name, fileName, fields, t, func(raw string) error {
+			return l.setMapEntries(v, raw, sep, kvSep)
+		})
+		for _, key := range l.env.keys(name + "_") {
+			if name+"_"+key == fileName {
+				continue
+			}
+			raw, _ := l.env.getString(name + "_" + key)
+			if err := l.setMapEntry(v, key, raw, true); err != nil {
+				l.fail(name+"_"+key, entryPath(key), raw, t.Elem(), err)
+			}
+		}
+		return
+	}
+
+	for _, key := range mapStructKeys(l.env.keys(name+"_"), t.Elem()) {
+		k, err := l.mapKey(v, key, true)
+		if err != nil {
+			l.fail(name+"_"+key, entryPath(key), key, t.Key(), err)
+			continue
+		}
+		elem := reflect.New(t.Elem()).Elem()
+		if existing := v.MapIndex(k); existing.IsValid() {
+			elem.Set(existing)
+		}
+		if elem.Kind() == reflect.Pointer && elem.IsNil() {
+			elem.Set(reflect.New(t.Elem().Elem()))
+		}
+		l.applyEnv(elem, append(segments[:len(segments):len(segments)], key), entryPath(fmt.Sprint(k)))
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(t))
+		}
+		v.SetMapIndex(k, elem)
+	}
+}
+
+// applyIndexed applies NAME_<i>_<FIELD> variables to the struct elements of a
+// slice or array. Elements that already exist (e.g. loaded from a file) are
+// updated in place. Slices grow by the indices that directly follow their last
+// element, so the indices must be contiguous; others are reported as out of
+// range rather than allocating up to an arbitrary index.
+func (l *envLoader) applyIndexed(v reflect.Value, name string, segments, fields []string) {
+	indices := l.env.indices(name + "_")
+	if len(indices) == 0 || !v.CanSet() {
+		return
+	}
+	if v.Kind() == reflect.Slice {
+		n := v.Len()
+		for _, i := range indices {
+			if i == n {
+				n++
+			}
+		}
+		if n > v.Len() {
+			grown := reflect.MakeSlice(v.Type(), n, n)
+			reflect.Copy(grown, v)
+			v.Set(grown)
+		}
+	}
+	last := fields[len(fields)-1]
+	for _, i := range indices {
+		idx := strconv.Itoa(i)
+		path := append(fields[:len(fields)-1:len(fields)-1], last+"["+idx+"]")
+		if i >= v.Len() {
+			l.fail(name+"_"+idx, path, "", v.Type(), fmt.Errorf("index %d out of range for length %d", i, v.Len()))
+			continue
+		}
+		elem := v.Index(i)
+		if elem.Kind() == reflect.Pointer && elem.IsNil() {
+			elem.Set(reflect.New(elem.Type().Elem()))
+		}
+		l.applyEnv(elem, append(segments[:len(segments):len(segments)], idx), path)
+	}
+}
+
+// fail records an error for the variable name mapped to the field at path.
+func (l *envLoader) fail(name string, path []string, raw string, typ reflect.Type, err error) {
+	l.errs = append(l.errs, &EnvError{
+		Var:   name,
+		Field: strings.Join(path, "."),
+		Value: raw,
+		Type:  typ,
+		Err:   err,
+	})
+}
+
+// envSegment returns the env name segment of a struct field: its `env` tag or
+// its name in SCREAMING_SNAKE_CASE. ok is false for fields tagged env:"-".
+func envSegment(sf reflect.StructField) (seg string, ok bool) {
+	tag := sf.Tag.Get(envVarTagName)
+	switch tag {
+	case "-":
+		return "", false
+	case "":
+		return toScreamingSnake(sf.Name), true
+	default:
+		return tag, true
+	}
+}
+
+// mapStructKeys extracts the distinct map keys from variable name suffixes of the
+// form <KEY>_<FIELD>[_...], where FIELD is an env segment of the struct type t.
+// Keys may contain underscores; the longest key that leaves a known field is used.
+func mapStructKeys(suffixes []string, t reflect.Type) []string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var segs []string
+	for i := 0; i < t.NumField(); i++ {
+		if sf := t.Field(i); sf.PkgPath == "" {
+			if seg, ok := envSegment(sf); ok {
+				segs = append(segs, seg)
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	var keys []string
+	for _, suffix := range suffixes {
+		key := ""
+		for i := len(suffix) - 1; i > 0; i-- {
+			if suffix[i] != '_' {
+				continue
+			}
+			rest := suffix[i+1:]
+			for _, seg := range segs {
+				if rest == seg || strings.HasPrefix(rest, seg+"_") {
+					key = suffix[:i]
+					break
+				}
+			}
+			if key != "" {
+				break
+			}
+		}
+		if key != "" && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// setMapEntries parses raw as entries separated by sep, each holding a key and a
+// value separated by kvSep, and stores them in the map v.
+func (l *envLoader) setMapEntries(v reflect.Value, raw, sep, kvSep string) error {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	for i, entry := range strings.Split(raw, sep) {
+		key, val, ok := strings.Cut(entry, kvSep)
+		if !ok {
+			return fmt.Errorf("entry %d: missing %q between key and value", i, kvSep)
+		}
+		if err := l.setMapEntry(v, strings.TrimSpace(key), strings.TrimSpace(val), false); err != nil {
+			return fmt.Errorf("entry %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// setMapEntry parses raw into a new value of the map element type and stores it
+// under key, allocating the map if needed.
+func (l *envLoader) setMapEntry(v reflect.Value, key, raw string, fold bool) error {
+	k, err := l.mapKey(v, key, fold)
+	if err != nil {
+		return fmt.Errorf("key %q: %w", key, err)
+	}
+	elem := reflect.New(v.Type().Elem()).Elem()
+	if err := l.setScalar(elem, raw); err != nil {
+		return err
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+	v.SetMapIndex(k, elem)
+	return nil
+}
+
+// mapKey converts key to the key type of the map v. With fold set, string keys
+// reuse an existing key that matches case insensitively, or are lowercased.
+func (l *envLoader) mapKey(v reflect.Value, key string, fold bool) (reflect.Value, error) {
+	kt := v.Type().Key()
+	if kt.Kind() == reflect.String && fold {
+		iter := v.MapRange()
+		for iter.Next() {
+			if strings.EqualFold(iter.Key().String(), key) {
+				return iter.Key(), nil
+			}
+		}
+		key = strings.ToLower(key)
+	}
+	k := reflect.New(kt).Elem()
+	if err := l.setScalar(k, key); err != nil {
+		return reflect.Value{}, err
+	}
+	return k, nil
+}
+
+// isList reports whether t is a slice or an array.
+func isList(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice || t.Kind() == reflect.Array
+}
+
+// isStruct reports whether t is a struct or a pointer to a struct.
+func isStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// isScalar reports whether t (or the type t points to) can be parsed from a
+// single environment variable value by setScalar: it has a registered decoder,
+// implements encoding.TextUnmarshaler or json.Unmarshaler, or has a supported kind.
+func (l *envLoader) isScalar(t reflect.Type) bool {
+	if _, ok := l.decoders[t]; ok {
+		return true
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		if _, ok := l.decoders[t]; ok {
+			return true
+		}
+	}
+	if pt := reflect.PointerTo(t); pt.Implements(textUnmarshalerType) || pt.Implements(jsonUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Struct:
+		return t == bigIntType || t == bigFloatType
+	}
+	return false
+}
+
+// setList splits raw on sep and parses every element (with surrounding whitespace
+// trimmed) into a new slice, or into an array that must be long enough to hold all
+// elements. An empty raw value yields an empty slice or a zeroed array.
+func (l *envLoader) setList(v reflect.Value, raw, sep string) error {
+	var parts []string
+	if strings.TrimSpace(raw) != "" {
+		parts = strings.Split(raw, sep)
+	}
+	var out reflect.Value
+	if v.Kind() == reflect.Array {
+		if len(parts) > v.Len() {
+			return fmt.Errorf("%d elements exceed array length %d", len(parts), v.Len())
+		}
+		out = reflect.New(v.Type()).Elem()
+	} else {
+		out = reflect.MakeSlice(v.Type(), len(parts), len(parts))
+	}
+	for i, part := range parts {
+		if err := l.setScalar(out.Index(i), strings.TrimSpace(part)); err != nil {
+			return fmt.Errorf("element %d: %w", i, err)
+		}
+	}
+	v.Set(out)
+	return nil
+}
+
+// setScalar parses raw into v, which must be addressable. A decoder registered for
+// the type of v takes precedence, then big.Int/big.Float, then UnmarshalText and
+// UnmarshalJSON methods, and finally parsing according to the kind of v. Sized
+// integer, float and complex kinds are range-checked against their bit size.
+// Pointers are allocated on success only, so a parse failure leaves v untouched.
+func (l *envLoader) setScalar(v reflect.Value, raw string) error {
+	if fn, ok := l.decoders[v.Type()]; ok {
+		return setDecoded(v, fn, raw)
+	}
+	if v.Kind() == reflect.Pointer {
+		elem := reflect.New(v.Type().Elem())
+		if err := l.setScalar(elem.Elem(), raw); err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(elem)
+		} else {
+			v.Elem().Set(elem.Elem())
+		}
+		return nil
+	}
+	if t := v.Type(); t != bigIntType && t != bigFloatType {
+		if ok, err := setUnmarshaled(v, raw); ok {
+			return err
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType {
+			d, err := time.ParseDuration(strings.TrimSpace(raw))
+			if err != nil {
+				return err
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.TrimSpace(raw), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(raw), v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Complex64, reflect.Complex128:
+		c, err := strconv.ParseComplex(strings.TrimSpace(raw), v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetComplex(c)
+	case reflect.Struct:
+		return setBig(v, strings.TrimSpace(raw))
+	default:
+		return fmt.Errorf("unsupported kind %s", v.Kind())
+	}
+	return nil
+}
+
+// setDecoded stores the result of a registered decoder in v. The decoder may return
+// a value of the type of v, a pointer to it, or the element type when v is a pointer.
+func setDecoded(v reflect.Value, fn func(string) (any, error), raw string) error {
+	out, err := fn(raw)
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(out)
+	t := v.Type()
+	switch {
+	case !rv.IsValid():
+		return fmt.Errorf("decoder for %s returned nil", t)
+	case rv.Type().AssignableTo(t):
+		v.Set(rv)
+	case rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Type().Elem().AssignableTo(t):
+		v.Set(rv.Elem())
+	case t.Kind() == reflect.Pointer && rv.Type().AssignableTo(t.Elem()):
+		p := reflect.New(t.Elem())
+		p.Elem().Set(rv)
+		v.Set(p)
+	default:
+		return fmt.Errorf("decoder for %s returned %s", t, rv.Type())
+	}
+	return nil
+}
+
+// setUnmarshaled decodes raw into v with its UnmarshalText or UnmarshalJSON method
+// and reports whether v has one of them. Values that are not valid JSON are passed
+// to UnmarshalJSON as a JSON string. The method runs on a copy, so v is only
+// updated on success.
+func setUnmarshaled(v reflect.Value, raw string) (bool, error) {
+	tmp := reflect.New(v.Type())
+	switch u := tmp.Interface().(type) {
+	case encoding.TextUnmarshaler:
+		if err := u.UnmarshalText([]byte(raw)); err != nil {
+			return true, err
+		}
+	case json.Unmarshaler:
+		data := []byte(raw)
+		if !json.Valid(data) {
+			data, _ = json.Marshal(raw)
+		}
+		if err := u.UnmarshalJSON(data); err != nil {
+			return true, err
+		}
+	default:
+		return false, nil
+	}
+	v.Set(tmp.Elem())
+	return true, nil
+}
+
+// setBig parses raw into a big.Int (decimal, or with a 0x, 0o or 0b prefix) or a
+// big.Float (with enough precision to represent every given digit).
+func setBig(v reflect.Value, raw string) error {
+	switch v.Type() {
+	case bigIntType:
+		n, ok := new(big.Int).SetString(raw, 0)
+		if !ok {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.Set(reflect.ValueOf(n).Elem())
+	case bigFloatType:
+		prec := uint(4 * len(raw))
+		if prec < 64 {
+			prec = 64
+		}
+		f, _, err := big.ParseFloat(raw, 10, prec, big.ToNearestEven)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(f).Elem())
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package config
+
+import "os"
+
+// WithLookupEnv sets the function used to look up environment variables instead
+// of os.LookupEnv, e.g. to read them from a map in tests. It is used for the
+// environment overrides, ${PREFIX}_CONFIG_PATH, ${PREFIX}_PROFILE, the XDG
+// variables and interpolation. Since variables cannot be listed through it, map
+// keys, slice indices and nested pointer fields are only discovered from the
+// variables listed by WithEnviron, if set. Panics if fn is nil.
+func WithLookupEnv[T any](fn func(string) (string, bool)) Option[T] {
+	return func(m *Provider[T]) {
+		if fn == nil {
+			panic("config: WithLookupEnv: fn cannot be nil")
+		}
+		m.lookupEnv = fn
+	}
+}
+
+// WithEnviron sets the function returning the environment as "NAME=value"
+// entries, in the format of os.Environ, which it replaces. Unlike WithLookupEnv,
+// it lists the variables, so that map keys, slice indices and nested pointer
+// fields can be discovered. If both are set, variables are looked up with the
+// WithLookupEnv function first. The function is called on every load. Panics if
+// fn is nil.
+func WithEnviron[T any](fn func() []string) Option[T] {
+	return func(m *Provider[T]) {
+		if fn == nil {
+			panic("config: WithEnviron: fn cannot be nil")
+		}
+		m.environ = fn
+	}
+}
+
+// env returns the environment set with WithLookupEnv and WithEnviron, or the
+// process environment.
+func (m *Provider[T]) env() envVars {
+	switch {
+	case m.environ != nil:
+		return envVars{lookup: m.lookupEnv, vars: parseEnviron(m.environ())}
+	case m.lookupEnv != nil:
+		return envVars{lookup: m.lookupEnv}
+	}
+	return processEnv()
+}
+
+// getenv returns the value of the named variable in the Provider's environment,
+// or "" if it is unset.
+func (m *Provider[T]) getenv(name string) string {
+	if m.lookupEnv == nil && m.environ == nil {
+		return os.Getenv(name)
+	}
+	v, _ := m.env().getString(name)
+	return v
+}
+
+// envLookup returns the function used to look up interpolated variables, or nil
+// for os.LookupEnv.
+func (m *Provider[T]) envLookup() func(string) (string, bool) {
+	if m.lookupEnv == nil && m.environ == nil {
+		return nil
+	}
+	return m.env().getString
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// mapLookup returns a WithLookupEnv function reading from env.
+func mapLookup(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+func TestProvider_Get_LookupEnv(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yml")
+	writeFile(t, path, "name: ${APP_NAME}\nport: 1\n")
+	writeFile(t, filepath.Join(dir, "config.staging.yml"), "port: 2\n")
+
+	tests := []struct {
+		name      string
+		opts      []Option[filesCfg]
+		wantName  string
+		wantPort  int
+		wantLabel string
+		wantHosts []string
+	}{
+		{
+			name: "lookup",
+			opts: []Option[filesCfg]{WithLookupEnv[filesCfg](mapLookup(map[string]string{
+				"MYAPP_CONFIG_PATH": path,
+				"MYAPP_PROFILE":     "staging",
+				"APP_NAME":          "lookup",
+				"MYAPP_HOSTS":       "a,b",
+				"MYAPP_LABELS_TEAM": "not discovered",
+			}))},
+			wantName:  "lookup",
+			wantPort:  2,
+			wantHosts: []string{"a", "b"},
+		},
+		{
+			name: "environ",
+			opts: []Option[filesCfg]{WithEnviron[filesCfg](func() []string {
+				return []string{
+					"MYAPP_CONFIG_PATH=" + path,
+					"APP_NAME=environ",
+					"MYAPP_PORT=3",
+					"MYAPP_LABELS_TEAM=core",
+					"MYAPP_HOSTS=c",
+				}
+			})},
+			wantName:  "environ",
+			wantPort:  3,
+			wantLabel: "core",
+			wantHosts: []string{"c"},
+		},
+		{
+			name: "lookup before environ",
+			opts: []Option[filesCfg]{
+				WithLookupEnv[filesCfg](mapLookup(map[string]string{"MYAPP_CONFIG_PATH": path, "MYAPP_LABELS_TEAM": "lookup"})),
+				WithEnviron[filesCfg](func() []string { return []string{"APP_NAME=environ", "MYAPP_LABELS_TEAM=environ"} }),
+			},
+			wantName:  "environ",
+			wantPort:  1,
+			wantLabel: "lookup",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			p := New[filesCfg](append([]Option[filesCfg]{WithEnvPrefix[filesCfg]("MYAPP"), WithInterpolation[filesCfg](true)}, tc.opts...)...)
+			cfg, gotPath, _, err := p.Get()
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if gotPath != path {
+				t.Fatalf("path = %q, want %q", gotPath, path)
+			}
+			if cfg.Name != tc.wantName || cfg.Port != tc.wantPort || cfg.Labels["team"] != tc.wantLabel || !reflect.DeepEqual(cfg.Hosts, tc.wantHosts) {
+				t.Fatalf("cfg = %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestProvider_Get_LookupEnv_DotEnv(t *testing.T) {
+	t.Parallel()
+	dotEnv := filepath.Join(t.TempDir(), ".env")
+	writeFile(t, dotEnv, "MYAPP_NAME=dotenv\nMYAPP_PORT=80\n")
+	p := New[filesCfg](
+		WithEnvPrefix[filesCfg]("MYAPP"),
+		WithDotEnv[filesCfg](dotEnv),
+		WithLookupEnv[filesCfg](mapLookup(map[string]string{"MYAPP_PORT": "8080"})),
+	)
+	cfg, _, _, err := p.Get()
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if cfg.Name != "dotenv" || cfg.Port != 8080 {
+		t.Fatalf("cfg = %+v, want the lookup to override the dotenv file", cfg)
+	}
+}
@@ -0,0 +1,354 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// SliceMergePolicy controls how slices are combined when several config files
+// are merged.
+type SliceMergePolicy int
+
+const (
+	// SliceReplace makes a slice in a later file replace the earlier one. This is
+	// the default.
+	SliceReplace SliceMergePolicy = iota
+	// SliceAppend appends the elements of a slice in a later file to the earlier one.
+	SliceAppend
+)
+
+// WithFiles adds config files that are merged in the given order before the
+// resolved config file (WithPersistence or <PREFIX>_CONFIG_PATH), which stays the
+// top file layer. Typical layers are a system default, a user file and a
+// project-local file. Files that do not exist are skipped; existing files are
+// deep-merged over the previous ones: mappings are merged key by key and slices
+// are combined according to WithSliceMerge. A config file created by
+// WithPersistence does not contain the keys these files set, so that later edits
+// of them take effect. Panics if a path is empty.
+func WithFiles[T any](paths ...string) Option[T] {
+	return func(m *Provider[T]) {
+		for _, p := range paths {
+			if p == "" {
+				panic("config: WithFiles: path cannot be empty")
+			}
+		}
+		m.layerFiles = append(m.layerFiles, paths...)
+	}
+}
+
+// WithSliceMerge sets how slices are combined when several config files are
+// merged. Defaults to SliceReplace. Panics on an unknown policy.
+func WithSliceMerge[T any](policy SliceMergePolicy) Option[T] {
+	return func(m *Provider[T]) {
+		if policy != SliceReplace && policy != SliceAppend {
+			panic("config: WithSliceMerge: unknown slice merge policy")
+		}
+		m.sliceMerge = policy
+	}
+}
+
+// Files returns the config files that contributed to the current configuration,
+// in merge order, including the resolved config file if it was loaded or created.
+// Like Get, it initializes the Provider if needed; it returns nil if
+// initialization failed.
+func (m *Provider[T]) Files() []string {
+	m.ensureInit(context.Background())
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.initErr != nil {
+		return nil
+	}
+	return append([]string(nil), m.loadedFiles...)
+}
+
+// filePaths returns the distinct, non-empty config file paths in merge order, with
+// the resolved config path last.
+func filePaths(layers []string, path string) []string {
+	seen := make(map[string]bool, len(layers)+1)
+	var paths []string
+	for _, p := range append(append([]string(nil), layers...), path) {
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// fileIO reads and writes config files with a set of codecs.
+type fileIO struct {
+	fsys        fs.FS // nil means the OS file system, see WithFS
+	codecs      codecSet
+	strict      bool // reject unknown keys, see WithStrict
+	interpolate bool // expand variable references, see WithInterpolation
+	includes    bool // resolve include directives, see WithIncludes
+	// rcNames are the WithUpwardSearch names; those without an extension are YAML.
+	rcNames []string
+	// projects are the files found by WithUpwardSearch, which may hold the root
+	// directive.
+	projects []string
+	// lookupEnv looks up interpolated variables; nil means os.LookupEnv.
+	lookupEnv func(string) (string, bool)
+}
+
+// directive reports whether key, with value at the top level of the file at
+// path, is a directive for the Provider rather than a config value.
+func (f fileIO) directive(path, key string, value interface{}) bool {
+	switch key {
+	case includeKey:
+		return f.includes
+	case rootKey:
+		_, ok := value.(bool)
+		return ok && slices.Contains(f.projects, path)
+	}
+	return false
+}
+
+// codec returns the codec for the file at path, selected by its extension. A
+// WithUpwardSearch name without an extension is YAML, as in writeFile.
+func (f fileIO) codec(path string) (Codec, error) {
+	ext := filepath.Ext(path)
+	codec, err := f.codecs.lookup(ext)
+	if err != nil && extensionless(filepath.Base(path)) && slices.Contains(f.rcNames, filepath.Base(path)) {
+		if codec, err = f.codecs.lookup(".yaml"); err != nil {
+			codec, err = yamlCodec{}, nil
+		}
+	}
+	return codec, err
+}
+
+// existingFiles returns the paths that exist. Every path must have a codec for
+// its extension, even if the file is missing.
+func (f fileIO) existingFiles(paths []string) ([]string, error) {
+	var existing []string
+	for _, p := range paths {
+		if _, err := f.codec(p); err != nil {
+			return nil, err
+		}
+		if _, err := f.stat(p); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("read %s: %w", p, err)
+		}
+		existing = append(existing, p)
+	}
+	return existing, nil
+}
+
+// loadFiles decodes the given existing files, with their includes, into cfg and
+// returns the files that contributed, in merge order. A single file is decoded
+// directly. Several files are first decoded into generic trees, which are merged
+// in order; the result is then decoded into cfg in the format of the last file,
+// so files of different formats must use the same keys.
+func (f fileIO) loadFiles(paths []string, cfg interface{}, policy SliceMergePolicy) ([]string, error) {
+	l := &fileLoad{fileIO: f, cfg: cfg, policy: policy}
+	var merged interface{}
+	for _, p := range paths {
+		tree, err := l.tree(p, nil)
+		if err != nil {
+			return nil, err
+		}
+		merged = l.merge(merged, tree)
+	}
+	if err := errors.Join(l.unknown...); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(l.files) == 0:
+		return nil, nil
+	case len(l.files) == 1 && !l.rewritten:
+		return l.files, f.loadFile(l.files[0], cfg)
+	case merged == nil:
+		return l.files, nil
+	}
+
+	codec, err := f.codec(paths[len(paths)-1])
+	if err != nil {
+		return nil, err
+	}
+	data, err := codec.Marshal(merged)
+	if err == nil {
+		err = codec.Unmarshal(data, cfg)
+	}
+	if err != nil {
+		return nil, l.locate(err)
+	}
+	return l.files, nil
+}
+
+// readTree decodes the file at path into a generic tree of map[string]interface{},
+// []interface{} and scalar values.
+func (f fileIO) readTree(path string) (interface{}, error) {
+	codec, data, err := f.read(path)
+	if err != nil {
+		return nil, err
+	}
+	var tree interface{}
+	if err := codec.Unmarshal(data, &tree); err != nil {
+		return nil, newParseError(path, data, err)
+	}
+	return normalizeTree(tree), nil
+}
+
+// checkUnknown returns the keys of the file at path that do not map to a field of
+// cfg, as *UnknownFieldError values joined together. Files whose codec does not
+// implement StrictCodec are not checked.
+func (f fileIO) checkUnknown(path string, cfg interface{}) error {
+	codec, data, err := f.read(path)
+	if err != nil {
+		return err
+	}
+	sc, ok := codec.(StrictCodec)
+	if !ok {
+		return nil
+	}
+	unknown, err := sc.UnknownFields(data, cfg)
+	if err != nil {
+		return newParseError(path, data, err)
+	}
+	var (
+		errs []error
+		top  map[string]interface{} // decoded when a key may be a directive
+	)
+	for _, u := range unknown {
+		if u.Key == includeKey || u.Key == rootKey {
+			if top == nil {
+				tree, err := f.readTree(path)
+				if err != nil {
+					return err
+				}
+				if top, _ = tree.(map[string]interface{}); top == nil {
+					top = map[string]interface{}{}
+				}
+			}
+			if f.directive(path, u.Key, top[u.Key]) {
+				continue
+			}
+		}
+		u.Path = path
+		errs = append(errs, u)
+	}
+	return errors.Join(errs...)
+}
+
+// normalizeTree converts YAML mappings with non-string keys into
+// map[string]interface{} so that trees from all formats merge and encode alike.
+func normalizeTree(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = normalizeTree(e)
+		}
+		return t
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[fmt.Sprint(k)] = normalizeTree(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range t {
+			t[i] = normalizeTree(e)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// layerTree returns the merged tree of the given existing files, with their
+// includes, or nil if there are none.
+func (f fileIO) layerTree(paths []string, policy SliceMergePolicy) (interface{}, error) {
+	f.strict = false
+	l := &fileLoad{fileIO: f, policy: policy}
+	var merged interface{}
+	for _, p := range paths {
+		tree, err := l.tree(p, nil)
+		if err != nil {
+			return nil, err
+		}
+		merged = l.merge(merged, tree)
+	}
+	return merged, nil
+}
+
+// fileTree returns cfg as a generic tree, as it would be written to the file at
+// path.
+func (f fileIO) fileTree(path string, cfg interface{}) (interface{}, error) {
+	codec, err := f.codecs.lookup(filepath.Ext(path))
+	if filepath.Ext(path) == "" {
+		codec, err = yamlCodec{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, err := codec.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%w as %s: %w", ErrFormat, filepath.Ext(path), err)
+	}
+	var tree interface{}
+	if err := codec.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("%w as %s: %w", ErrFormat, filepath.Ext(path), err)
+	}
+	return normalizeTree(tree), nil
+}
+
+// pruneTree removes the keys set in layer from tree, descending into mappings
+// present in both. Mappings left empty are removed too.
+func pruneTree(tree, layer interface{}) interface{} {
+	t, ok := tree.(map[string]interface{})
+	l, lok := layer.(map[string]interface{})
+	if !ok || !lok {
+		return tree
+	}
+	for k, lv := range l {
+		tv, found := t[k]
+		if !found {
+			continue
+		}
+		sub, isMap := tv.(map[string]interface{})
+		if _, lIsMap := lv.(map[string]interface{}); isMap && lIsMap {
+			if pruneTree(sub, lv); len(sub) > 0 {
+				continue
+			}
+		}
+		delete(t, k)
+	}
+	return t
+}
+
+// mergeTrees merges src over dst: mappings are merged key by key, slices are
+// replaced or appended according to policy and any other value in src wins.
+func mergeTrees(dst, src interface{}, policy SliceMergePolicy) interface{} {
+	switch s := src.(type) {
+	case map[string]interface{}:
+		d, ok := dst.(map[string]interface{})
+		if !ok {
+			return s
+		}
+		for k, v := range s {
+			if prev, ok := d[k]; ok {
+				d[k] = mergeTrees(prev, v, policy)
+			} else {
+				d[k] = v
+			}
+		}
+		return d
+	case []interface{}:
+		if d, ok := dst.([]interface{}); ok && policy == SliceAppend {
+			return append(d, s...)
+		}
+		return s
+	default:
+		return src
+	}
+}
@@ -0,0 +1,327 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WritableFS is an fs.FS that can also create directories and write files. With
+// WithFS, a WritableFS is needed to create the config file with WithPersistence.
+// Names are slash-separated and unrooted, as for fs.FS.
+type WritableFS interface {
+	fs.FS
+	MkdirAll(name string, perm fs.FileMode) error
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+}
+
+// WithFS makes the Provider read config files from fsys instead of the OS file
+// system, e.g. defaults embedded with embed.FS or a MemFS in tests. Config file
+// paths, including the resolved config path, are used as fs.FS names: they are
+// made slash-separated and the leading "/" is dropped, so "/etc/myapp/config.yml"
+// is read as "etc/myapp/config.yml". Files are created only if fsys implements
+// WritableFS. Watch polls fsys too. Panics if fsys is nil.
+func WithFS[T any](fsys fs.FS) Option[T] {
+	return func(m *Provider[T]) {
+		if fsys == nil {
+			panic("config: WithFS: fsys cannot be nil")
+		}
+		m.fsys = fsys
+	}
+}
+
+// errReadOnlyFS is returned when a config file has to be created in a file
+// system set with WithFS that is not a WritableFS.
+var errReadOnlyFS = errors.New("file system is not writable")
+
+// fsName returns the fs.FS name for a file path.
+func fsName(p string) string {
+	p = filepath.ToSlash(strings.TrimPrefix(p, filepath.VolumeName(p)))
+	if p = strings.TrimPrefix(path.Clean(p), "/"); p == "" {
+		return "."
+	}
+	return p
+}
+
+func (f fileIO) readFile(p string) ([]byte, error) {
+	if f.fsys == nil {
+		return os.ReadFile(p)
+	}
+	return fs.ReadFile(f.fsys, fsName(p))
+}
+
+func (f fileIO) stat(p string) (fs.FileInfo, error) {
+	if f.fsys == nil {
+		return os.Stat(p)
+	}
+	return fs.Stat(f.fsys, fsName(p))
+}
+
+func (f fileIO) readDir(dir string) ([]fs.DirEntry, error) {
+	if f.fsys == nil {
+		return os.ReadDir(dir)
+	}
+	return fs.ReadDir(f.fsys, fsName(dir))
+}
+
+func (f fileIO) glob(pattern string) ([]string, error) {
+	if f.fsys == nil {
+		return filepath.Glob(pattern)
+	}
+	return fs.Glob(f.fsys, fsName(pattern))
+}
+
+// abs returns a canonical form of p, used to detect include cycles.
+func (f fileIO) abs(p string) (string, error) {
+	if f.fsys == nil {
+		return filepath.Abs(p)
+	}
+	return fsName(p), nil
+}
+
+// ensurePath is EnsurePath for the file system of f. A file system that is not
+// writable is left as is; writing the file then fails.
+func (f fileIO) ensurePath(p string) error {
+	if f.fsys == nil {
+		return EnsurePath(p)
+	}
+	info, err := f.stat(p)
+	switch {
+	case err == nil:
+		if info.IsDir() {
+			return ErrInaccessiblePath
+		}
+		return nil
+	case !errors.Is(err, fs.ErrNotExist):
+		return ErrInaccessiblePath
+	}
+	wfs, ok := f.fsys.(WritableFS)
+	if !ok {
+		return nil
+	}
+	if err := wfs.MkdirAll(path.Dir(fsName(p)), 0o700); err != nil {
+		return ErrCannotCreateDirectories
+	}
+	return nil
+}
+
+// writeFS writes data to the file at p in the file system of f.
+func (f fileIO) writeFS(p string, data []byte) error {
+	wfs, ok := f.fsys.(WritableFS)
+	if !ok {
+		return fmt.Errorf("%s: %w", p, errReadOnlyFS)
+	}
+	if err := wfs.WriteFile(fsName(p), data, 0o600); err != nil {
+		return fmt.Errorf("%w %s: %w", ErrWrite, p, err)
+	}
+	return nil
+}
+
+// MemFS is an in-memory WritableFS, e.g. to run a Provider in tests without
+// touching the disk. The zero value is an empty file system ready to use. It is
+// safe for concurrent use.
+type MemFS struct {
+	mu    sync.RWMutex
+	files map[string]*memFile // by name; directories are kept if created by MkdirAll
+}
+
+// memFile is a file or directory in a MemFS. It is replaced, never modified, so
+// that open files keep reading the contents they were opened with.
+type memFile struct {
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// impliedDir is a directory that exists only because files are below it.
+var impliedDir = &memFile{mode: fs.ModeDir | 0o555}
+
+// Open implements fs.FS.
+func (m *MemFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, ok := m.files[name]
+	switch {
+	case ok && !f.mode.IsDir():
+		return &memReader{Reader: bytes.NewReader(f.data), info: memInfo{path.Base(name), f}}, nil
+	case !ok && !m.isDir(name):
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case !ok:
+		f = impliedDir
+	}
+
+	prefix := name + "/"
+	if name == "." {
+		prefix = ""
+	}
+	seen := map[string]bool{}
+	var entries []fs.DirEntry
+	for n, e := range m.files {
+		rest, found := strings.CutPrefix(n, prefix)
+		if !found || rest == "" {
+			continue
+		}
+		child, _, below := strings.Cut(rest, "/")
+		if seen[child] {
+			continue
+		}
+		seen[child] = true
+		if below {
+			if e, ok = m.files[prefix+child]; !ok {
+				e = impliedDir
+			}
+		}
+		entries = append(entries, memInfo{child, e})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return &memDir{info: memInfo{path.Base(name), f}, entries: entries}, nil
+}
+
+// MkdirAll creates the directory name and any missing parents.
+func (m *MemFS) MkdirAll(name string, perm fs.FileMode) error {
+	if !fs.ValidPath(name) {
+		return &fs.PathError{Op: "mkdir", Path: name, Err: fs.ErrInvalid}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if name == "." {
+		return nil
+	}
+	var dir string
+	for _, elem := range strings.Split(name, "/") {
+		dir = path.Join(dir, elem)
+		if f, ok := m.files[dir]; ok {
+			if !f.mode.IsDir() {
+				return &fs.PathError{Op: "mkdir", Path: name, Err: errors.New("not a directory")}
+			}
+			continue
+		}
+		if m.files == nil {
+			m.files = map[string]*memFile{}
+		}
+		m.files[dir] = &memFile{mode: fs.ModeDir | perm.Perm(), modTime: time.Now()}
+	}
+	return nil
+}
+
+// WriteFile writes data to the file name, creating or replacing it. The parent
+// directory must exist.
+func (m *MemFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrInvalid}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if f, ok := m.files[name]; ok && f.mode.IsDir() {
+		return &fs.PathError{Op: "write", Path: name, Err: errors.New("is a directory")}
+	}
+	if dir := path.Dir(name); !m.isDir(dir) {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrNotExist}
+	}
+	if m.files == nil {
+		m.files = map[string]*memFile{}
+	}
+	m.files[name] = &memFile{data: append([]byte(nil), data...), mode: perm.Perm(), modTime: time.Now()}
+	return nil
+}
+
+// Remove removes the file or empty directory name.
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[name]; !ok && !m.isDir(name) {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	for n := range m.files {
+		if strings.HasPrefix(n, name+"/") {
+			return &fs.PathError{Op: "remove", Path: name, Err: errors.New("directory not empty")}
+		}
+	}
+	delete(m.files, name)
+	return nil
+}
+
+// isDir reports whether name is a directory, created with MkdirAll or implied by
+// the files below it. The caller must hold m.mu.
+func (m *MemFS) isDir(name string) bool {
+	if name == "." {
+		return true
+	}
+	if f, ok := m.files[name]; ok {
+		return f.mode.IsDir()
+	}
+	for n := range m.files {
+		if strings.HasPrefix(n, name+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// memInfo describes an entry of a MemFS, as fs.FileInfo and fs.DirEntry.
+type memInfo struct {
+	name string
+	f    *memFile
+}
+
+func (i memInfo) Name() string               { return i.name }
+func (i memInfo) Size() int64                { return int64(len(i.f.data)) }
+func (i memInfo) Mode() fs.FileMode          { return i.f.mode }
+func (i memInfo) Type() fs.FileMode          { return i.f.mode.Type() }
+func (i memInfo) ModTime() time.Time         { return i.f.modTime }
+func (i memInfo) IsDir() bool                { return i.f.mode.IsDir() }
+func (i memInfo) Sys() any                   { return nil }
+func (i memInfo) Info() (fs.FileInfo, error) { return i, nil }
+
+// memReader is an open MemFS file.
+type memReader struct {
+	*bytes.Reader
+	info memInfo
+}
+
+func (r *memReader) Stat() (fs.FileInfo, error) { return r.info, nil }
+func (r *memReader) Close() error               { return nil }
+
+// memDir is an open MemFS directory, listing the entries it had when opened.
+type memDir struct {
+	info    memInfo
+	entries []fs.DirEntry
+	off     int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *memDir) Close() error               { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.off:]
+	if n <= 0 {
+		d.off = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(rest))
+	d.off += n
+	return rest[:n:n], nil
+}
+
+// Compile-time check that MemFS is a WritableFS.
+var _ WritableFS = (*MemFS)(nil)
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFSName(t *testing.T) {
+	tests := map[string]string{
+		"config.yml":                 "config.yml",
+		"/etc/myapp/config.yml":      "etc/myapp/config.yml",
+		"defaults//./config.yml":     "defaults/config.yml",
+		"/":                          ".",
+		filepath.Join("a", "b.json"): "a/b.json",
+	}
+	for in, want := range tests {
+		if got := fsName(in); got != want {
+			t.Errorf("fsName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestProvider_Get_FS(t *testing.T) {
+	t.Run("read-only fs", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"defaults/config.yml":       {Data: []byte("name: embedded\nport: 80\n")},
+			"defaults/conf.d/10-db.yml": {Data: []byte("include: ../db.yml\n")},
+			"defaults/db.yml":           {Data: []byte("db:\n  host: db.local\n")},
+		}
+		p := New[filesCfg](
+			WithFS[filesCfg](fsys),
+			WithFiles[filesCfg]("defaults/config.yml"),
+			WithConfigDir[filesCfg]("/defaults/conf.d"),
+			WithIncludes[filesCfg](),
+		)
+		cfg, _, _, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if cfg.Name != "embedded" || cfg.Port != 80 || cfg.DB.Host != "db.local" {
+			t.Fatalf("cfg = %+v", cfg)
+		}
+		want := []string{"defaults/config.yml", "/defaults/db.yml", "/defaults/conf.d/10-db.yml"}
+		if !reflect.DeepEqual(p.Files(), want) {
+			t.Fatalf("Files = %v, want %v", p.Files(), want)
+		}
+	})
+
+	t.Run("read-only fs cannot create the config file", func(t *testing.T) {
+		t.Setenv("XDG_CONFIG_HOME", "/cfg")
+		p := New[filesCfg](WithFS[filesCfg](fstest.MapFS{}), WithPersistence[filesCfg]("myapp"))
+		_, _, _, err := p.Get()
+		if !errors.Is(err, ErrWrite) || !errors.Is(err, errReadOnlyFS) {
+			t.Fatalf("err = %v, want ErrWrite", err)
+		}
+	})
+
+	t.Run("persistence in memory", func(t *testing.T) {
+		xdg := filepath.Join(t.TempDir(), "xdg")
+		t.Setenv("XDG_CONFIG_HOME", xdg)
+		mem := &MemFS{}
+		p := New[filesCfg](
+			WithDefaultFn(func() *filesCfg { return &filesCfg{Name: "default"} }),
+			WithFS[filesCfg](mem),
+			WithPersistence[filesCfg]("myapp"),
+			WithFileName[filesCfg]("config.json"),
+		)
+		cfg, path, created, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if !created || cfg.Name != "default" {
+			t.Fatalf("created=%v cfg=%+v", created, cfg)
+		}
+		data, err := fs.ReadFile(mem, fsName(path))
+		if err != nil {
+			t.Fatalf("read created file: %v", err)
+		}
+		if !strings.Contains(string(data), `"name": "default"`) {
+			t.Fatalf("created file = %q", data)
+		}
+		if _, err := os.Stat(xdg); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("disk touched: stat %s: %v", xdg, err)
+		}
+
+		if err := mem.WriteFile(fsName(path), []byte(`{"name": "edited"}`), 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := p.Reload(context.Background()); err != nil {
+			t.Fatalf("Reload: %v", err)
+		}
+		if cfg, _, _, _ := p.Get(); cfg.Name != "edited" {
+			t.Fatalf("after reload: cfg=%+v", cfg)
+		}
+	})
+}
+
+func TestMemFS(t *testing.T) {
+	mem := &MemFS{}
+
+	if err := mem.WriteFile("a/b.yml", []byte("x"), 0o600); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("WriteFile without parent: %v, want fs.ErrNotExist", err)
+	}
+	if err := mem.MkdirAll("a/b", 0o700); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := mem.WriteFile("a/b/c.yml", []byte("c: 1\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := mem.WriteFile("a/b", nil, 0o600); err == nil {
+		t.Fatalf("WriteFile over a directory succeeded")
+	}
+	if err := mem.MkdirAll("a/b/c.yml/d", 0o700); err == nil {
+		t.Fatalf("MkdirAll below a file succeeded")
+	}
+	if err := mem.WriteFile("../x", nil, 0o600); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("WriteFile invalid name: %v, want fs.ErrInvalid", err)
+	}
+
+	if err := fstest.TestFS(mem, "a/b/c.yml"); err != nil {
+		t.Fatalf("TestFS: %v", err)
+	}
+
+	f, err := mem.Open("a/b/c.yml")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := mem.WriteFile("a/b/c.yml", []byte("c: 2\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if data, err := io.ReadAll(f); err != nil || string(data) != "c: 1\n" {
+		t.Fatalf("open file reads %q, %v, want the contents it was opened with", data, err)
+	}
+	f.Close()
+
+	entries, err := fs.ReadDir(mem, "a/b")
+	if err != nil || len(entries) != 1 || entries[0].Name() != "c.yml" {
+		t.Fatalf("ReadDir = %v, %v", entries, err)
+	}
+	if err := mem.Remove("a/b"); err == nil {
+		t.Fatalf("Remove of a non-empty directory succeeded")
+	}
+	if err := mem.Remove("a/b/c.yml"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := fs.Stat(mem, "a/b/c.yml"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Stat removed file: %v", err)
+	}
+	if err := mem.Remove("a/b/c.yml"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Remove missing file: %v, want fs.ErrNotExist", err)
+	}
+	if err := fstest.TestFS(mem, "a/b"); err != nil {
+		t.Fatalf("TestFS: %v", err)
+	}
+}
@@ -5,3 +5,5 @@ go 1.22
 require gopkg.in/yaml.v3 v3.0.1
 
 require github.com/ygrebnov/model v0.1.0
+
+require github.com/BurntSushi/toml v1.5.0
@@ -0,0 +1,303 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// includeKey is the top-level key of the include directive, see WithIncludes.
+const includeKey = "include"
+
+// IncludeError reports an include directive that could not be resolved: a
+// missing or unreadable file, an invalid directive or an include cycle. Path is
+// the including file. It matches ErrInclude with errors.Is.
+type IncludeError struct {
+	Path    string // including config file
+	Include string // include entry as written, empty if the directive is invalid
+	Err     error
+}
+
+func (e *IncludeError) Error() string {
+	if e.Include == "" {
+		return fmt.Sprintf("%v %s: %v", ErrInclude, e.Path, e.Err)
+	}
+	return fmt.Sprintf("%v %s: %s: %v", ErrInclude, e.Path, e.Include, e.Err)
+}
+
+func (e *IncludeError) Unwrap() []error { return []error{ErrInclude, e.Err} }
+
+// WithIncludes enables the top-level `include:` directive in config files. Its
+// value is a path or a list of paths, relative to the including file, which may
+// be glob patterns (e.g. "conf.d/*.yml"). Included files are deep-merged in order
+// before the keys of the including file, which take precedence. They may include
+// other files; include cycles are reported as an *IncludeError.
+func WithIncludes[T any]() Option[T] {
+	return func(m *Provider[T]) {
+		m.includes = true
+	}
+}
+
+// WithConfigDir adds a directory of config fragments, such as /etc/myapp/conf.d.
+// Every file in dir with a registered extension is deep-merged in lexical order,
+// after the files added with WithFiles and before the resolved config file. Hidden
+// files are skipped, as is a missing directory. A config file created by
+// WithPersistence does not contain the keys the fragments set, so that later
+// edits of them take effect. Panics if dir is empty.
+func WithConfigDir[T any](dir string) Option[T] {
+	return func(m *Provider[T]) {
+		if dir == "" {
+			panic("config: WithConfigDir: dir cannot be empty")
+		}
+		m.configDirs = append(m.configDirs, dir)
+	}
+}
+
+// layerPaths returns the config file paths in merge order: the layers, the files
+// in dirs, then path.
+func (f fileIO) layerPaths(layers, dirs []string, path string) ([]string, error) {
+	paths := append([]string(nil), layers...)
+	for _, dir := range dirs {
+		files, err := f.dirFiles(dir)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, files...)
+	}
+	return filePaths(paths, path), nil
+}
+
+// dirFiles returns the files in dir that have a codec, in lexical order.
+func (f fileIO) dirFiles(dir string) ([]string, error) {
+	entries, err := f.readDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", dir, err)
+	}
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		if _, ok := f.codecs[filepath.Ext(name)]; ok {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	return files, nil
+}
+
+// fileLoad is the state of one loadFiles call.
+type fileLoad struct {
+	fileIO
+	cfg       interface{}
+	policy    SliceMergePolicy
+	files     []string // contributing files, in merge order
+	rewritten bool     // a directive was removed or a value expanded in a tree
+	unknown   []error  // unknown field errors in strict mode
+}
+
+// tree reads the file at path into a generic tree with its includes merged in.
+// chain holds the absolute paths of the including files.
+func (l *fileLoad) tree(path string, chain []string) (interface{}, error) {
+	if l.strict {
+		if err := l.checkUnknown(path, l.cfg); err != nil {
+			if !errors.Is(err, ErrUnknownField) {
+				return nil, err
+			}
+			l.unknown = append(l.unknown, err)
+		}
+	}
+	tree, rewritten, err := l.ownTree(path)
+	if err != nil {
+		return nil, err
+	}
+	l.rewritten = l.rewritten || rewritten
+	m, ok := tree.(map[string]interface{})
+	directive, found := m[includeKey]
+	if !l.includes || !ok || !found {
+		l.add(path)
+		return tree, nil
+	}
+	delete(m, includeKey)
+	l.rewritten = true
+
+	entries, err := includeEntries(directive)
+	if err != nil {
+		return nil, &IncludeError{Path: path, Err: err}
+	}
+	abs, err := l.abs(path)
+	if err != nil {
+		return nil, &IncludeError{Path: path, Err: err}
+	}
+	chain = append(chain[:len(chain):len(chain)], abs)
+
+	var merged interface{}
+	for _, entry := range entries {
+		matches, err := l.resolveInclude(path, entry)
+		if err != nil {
+			return nil, &IncludeError{Path: path, Include: entry, Err: err}
+		}
+		for _, match := range matches {
+			a, err := l.abs(match)
+			if err != nil {
+				return nil, &IncludeError{Path: path, Include: entry, Err: err}
+			}
+			for i, c := range chain {
+				if c == a {
+					cycle := strings.Join(append(chain[i:len(chain):len(chain)], a), " -> ")
+					return nil, &IncludeError{Path: path, Include: entry, Err: fmt.Errorf("include cycle: %s", cycle)}
+				}
+			}
+			sub, err := l.tree(match, chain)
+			if err != nil {
+				return nil, err
+			}
+			merged = l.merge(merged, sub)
+		}
+	}
+	l.add(path)
+	return l.merge(merged, m), nil
+}
+
+// ownTree reads the file at path into a generic tree, with its values expanded
+// and without the root directive, and reports whether this changed the tree.
+// The include directive is left to the caller.
+func (l *fileLoad) ownTree(path string) (interface{}, bool, error) {
+	tree, err := l.readTree(path)
+	if err != nil {
+		return nil, false, err
+	}
+	var rewritten bool
+	if l.interpolate {
+		if rewritten, err = l.expandTree(path, tree); err != nil {
+			return nil, false, err
+		}
+	}
+	m, _ := tree.(map[string]interface{})
+	if v, found := m[rootKey]; found && l.directive(path, rootKey, v) {
+		delete(m, rootKey)
+		rewritten = true
+	}
+	return tree, rewritten, nil
+}
+
+// locate returns the error for err, returned when decoding the merged tree of
+// the files into cfg. The merged document exists only in memory, so the problem
+// is reported in the last file whose own keys fail to decode, at the position
+// its decoder reports for the file as written.
+func (l *fileLoad) locate(err error) error {
+	pe := &ParseError{Path: strings.Join(l.files, ", "), Err: mergedError{err}}
+	scratch := func() interface{} { return reflect.New(reflect.TypeOf(l.cfg).Elem()).Interface() }
+	for i := len(l.files) - 1; i >= 0; i-- {
+		path := l.files[i]
+		if len(l.files) > 1 {
+			tree, _, err := l.ownTree(path)
+			if err != nil {
+				return err
+			}
+			if m, ok := tree.(map[string]interface{}); ok && l.includes {
+				delete(m, includeKey)
+			}
+			codec, err := l.codec(path)
+			if err != nil {
+				return err
+			}
+			data, err := codec.Marshal(tree)
+			if err == nil {
+				err = codec.Unmarshal(data, scratch())
+			}
+			if err == nil {
+				continue
+			}
+			pe = &ParseError{Path: path, Err: mergedError{err}}
+		}
+		var located *ParseError
+		if errors.As(l.loadFile(path, scratch()), &located) && located.Line > 0 {
+			return located
+		}
+		return pe
+	}
+	return pe
+}
+
+// mergedError is a decoder error for a document merged from several files,
+// without the line numbers its message gives in that document.
+type mergedError struct{ err error }
+
+var mergedLineRe = regexp.MustCompile(`\bline \d+(: | )?`)
+
+func (e mergedError) Error() string { return mergedLineRe.ReplaceAllString(e.err.Error(), "") }
+func (e mergedError) Unwrap() error { return e.err }
+
+// add records a contributing file, once.
+func (l *fileLoad) add(path string) {
+	for _, f := range l.files {
+		if f == path {
+			return
+		}
+	}
+	l.files = append(l.files, path)
+}
+
+func (l *fileLoad) merge(dst, src interface{}) interface{} {
+	if src == nil {
+		return dst
+	}
+	return mergeTrees(dst, src, l.policy)
+}
+
+// resolveInclude returns the files for an include entry of the file at path.
+// Patterns may match no file; a plain path must exist.
+func (l *fileLoad) resolveInclude(path, entry string) ([]string, error) {
+	p := entry
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(filepath.Dir(path), p)
+	}
+	if !strings.ContainsAny(entry, "*?[") {
+		if _, err := l.codecs.lookup(filepath.Ext(p)); err != nil {
+			return nil, err
+		}
+		if _, err := l.stat(p); err != nil {
+			return nil, err
+		}
+		return []string{p}, nil
+	}
+	matches, err := l.glob(p)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, m := range matches {
+		if _, ok := l.codecs[filepath.Ext(m)]; ok {
+			files = append(files, m)
+		}
+	}
+	return files, nil
+}
+
+// includeEntries returns the entries of an include directive value.
+func includeEntries(v interface{}) ([]string, error) {
+	switch t := v.(type) {
+	case string:
+		return []string{t}, nil
+	case []interface{}:
+		entries := make([]string, len(t))
+		for i, e := range t {
+			s, ok := e.(string)
+			if !ok || s == "" {
+				return nil, errors.New("include must be a path or a list of paths")
+			}
+			entries[i] = s
+		}
+		return entries, nil
+	default:
+		return nil, errors.New("include must be a path or a list of paths")
+	}
+}
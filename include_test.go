@@ -0,0 +1,190 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProvider_Get_Includes(t *testing.T) {
+	td := t.TempDir()
+
+	t.Run("nested includes and globs", func(t *testing.T) {
+		dir := filepath.Join(td, "nested")
+		path := filepath.Join(dir, "config.yml")
+		base := filepath.Join(dir, "base.json")
+		db := filepath.Join(dir, "parts", "db.yml")
+		labels := filepath.Join(dir, "parts", "labels.yml")
+		writeFile(t, path, "include:\n  - base.json\n  - parts/*.yml\nname: app\n")
+		writeFile(t, base, `{"include": "parts/db.yml", "name": "base", "port": 80, "hosts": ["a"]}`)
+		writeFile(t, db, "db:\n  host: db.local\n  user: root\n")
+		writeFile(t, labels, "labels:\n  env: prod\nport: 8080\n")
+		writeFile(t, filepath.Join(dir, "parts", "notes.txt"), "not a config file")
+
+		t.Setenv("MYAPP_CONFIG_PATH", path)
+		p := New[filesCfg](WithEnvPrefix[filesCfg]("MYAPP"), WithIncludes[filesCfg]())
+		cfg, _, _, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if cfg.Name != "app" || cfg.Port != 8080 || cfg.DB.Host != "db.local" || cfg.Labels["env"] != "prod" {
+			t.Fatalf("includes not merged: %+v", cfg)
+		}
+		if want := []string{db, base, labels, path}; !reflect.DeepEqual(p.Files(), want) {
+			t.Fatalf("Files = %v, want %v", p.Files(), want)
+		}
+	})
+
+	t.Run("strict accepts the directive", func(t *testing.T) {
+		dir := filepath.Join(td, "strict")
+		path := filepath.Join(dir, "config.yml")
+		writeFile(t, path, "include: base.yml\nname: app\n")
+		writeFile(t, filepath.Join(dir, "base.yml"), "port: 1\nbogus: true\n")
+
+		t.Setenv("MYAPP_CONFIG_PATH", path)
+		p := New[filesCfg](WithEnvPrefix[filesCfg]("MYAPP"), WithIncludes[filesCfg](), WithStrict[filesCfg]())
+		_, _, _, err := p.Get()
+		var ue *UnknownFieldError
+		if !errors.As(err, &ue) || ue.Key != "bogus" || ue.Path != filepath.Join(dir, "base.yml") {
+			t.Fatalf("err = %v, want unknown field bogus in base.yml", err)
+		}
+		if strings.Contains(err.Error(), "include") {
+			t.Fatalf("include directive reported as unknown: %v", err)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		dir := filepath.Join(td, "disabled")
+		path := filepath.Join(dir, "config.yml")
+		writeFile(t, path, "include: missing.yml\nname: app\n")
+
+		t.Setenv("MYAPP_CONFIG_PATH", path)
+		p := New[filesCfg](WithEnvPrefix[filesCfg]("MYAPP"))
+		cfg, _, _, err := p.Get()
+		if err != nil || cfg.Name != "app" {
+			t.Fatalf("Get = %+v, %v", cfg, err)
+		}
+	})
+
+	errCases := []struct {
+		name    string
+		files   map[string]string
+		wantErr string
+	}{
+		{
+			name:    "missing file",
+			files:   map[string]string{"config.yml": "include: missing.yml\n"},
+			wantErr: "config.yml: missing.yml: ",
+		},
+		{
+			name:    "cycle",
+			files:   map[string]string{"config.yml": "include: a.yml\n", "a.yml": "include: b.yml\n", "b.yml": "include: a.yml\n"},
+			wantErr: "b.yml: a.yml: include cycle: ",
+		},
+		{
+			name:    "self include",
+			files:   map[string]string{"config.yml": "include: config.yml\n"},
+			wantErr: "config.yml: config.yml: include cycle: ",
+		},
+		{
+			name:    "invalid directive",
+			files:   map[string]string{"config.yml": "include:\n  path: a.yml\n"},
+			wantErr: "config.yml: include must be a path or a list of paths",
+		},
+	}
+	for _, tc := range errCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := filepath.Join(td, strings.ReplaceAll(tc.name, " ", "-"))
+			for name, content := range tc.files {
+				writeFile(t, filepath.Join(dir, name), content)
+			}
+			t.Setenv("MYAPP_CONFIG_PATH", filepath.Join(dir, "config.yml"))
+			p := New[filesCfg](WithEnvPrefix[filesCfg]("MYAPP"), WithIncludes[filesCfg]())
+			_, _, _, err := p.Get()
+			var ie *IncludeError
+			if !errors.Is(err, ErrInclude) || !errors.As(err, &ie) {
+				t.Fatalf("err = %v, want *IncludeError", err)
+			}
+			if !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("err = %q, want it to contain %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestProvider_Get_ConfigDir(t *testing.T) {
+	td := t.TempDir()
+	dir := filepath.Join(td, "conf.d")
+	path := filepath.Join(td, "config.yml")
+	writeFile(t, filepath.Join(dir, "20-db.yml"), "db:\n  host: db.local\nport: 20\n")
+	writeFile(t, filepath.Join(dir, "10-base.json"), `{"name": "base", "port": 10}`)
+	writeFile(t, filepath.Join(dir, ".hidden.yml"), "name: hidden\n")
+	writeFile(t, filepath.Join(dir, "README.md"), "# fragments\n")
+	writeFile(t, filepath.Join(dir, "sub", "30-x.yml"), "name: sub\n")
+	writeFile(t, path, "db:\n  user: app\n")
+	t.Setenv("MYAPP_CONFIG_PATH", path)
+
+	p := New[filesCfg](
+		WithEnvPrefix[filesCfg]("MYAPP"),
+		WithConfigDir[filesCfg](dir),
+		WithConfigDir[filesCfg](filepath.Join(td, "missing.d")),
+	)
+	cfg, _, _, err := p.Get()
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if cfg.Name != "base" || cfg.Port != 20 || cfg.DB.Host != "db.local" || cfg.DB.User != "app" {
+		t.Fatalf("fragments not merged: %+v", cfg)
+	}
+	want := []string{filepath.Join(dir, "10-base.json"), filepath.Join(dir, "20-db.yml"), path}
+	if !reflect.DeepEqual(p.Files(), want) {
+		t.Fatalf("Files = %v, want %v", p.Files(), want)
+	}
+}
+
+func TestProvider_Get_ConfigDirCreate(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(td, "xdg"))
+	t.Setenv("XDG_CONFIG_DIRS", filepath.Join(td, "nosys"))
+	dir := filepath.Join(td, "conf.d")
+	writeFile(t, filepath.Join(dir, "10-base.yml"), "include: parts/db.yml\nname: base\n")
+	writeFile(t, filepath.Join(dir, "parts", "db.yml"), "db:\n  host: db.local\n")
+
+	p := New[filesCfg](
+		WithPersistence[filesCfg]("myapp"),
+		WithDefaultFn[filesCfg](func() *filesCfg { return &filesCfg{Name: "default", Port: 8080} }),
+		WithConfigDir[filesCfg](dir),
+		WithIncludes[filesCfg](),
+	)
+	cfg, path, created, err := p.Get()
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !created || cfg.Name != "base" || cfg.DB.Host != "db.local" || cfg.Port != 8080 {
+		t.Fatalf("created=%v cfg=%+v", created, cfg)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read created file: %v", err)
+	}
+	for _, s := range []string{"name:", "host:"} {
+		if strings.Contains(string(data), s) {
+			t.Fatalf("created file contains %q from the fragments: %q", s, data)
+		}
+	}
+
+	// Later edits of the fragments are not shadowed by the created file.
+	writeFile(t, filepath.Join(dir, "10-base.yml"), "include: parts/db.yml\nname: edited\n")
+	writeFile(t, filepath.Join(dir, "parts", "db.yml"), "db:\n  host: db.edited\n")
+	if err := p.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	cfg, _, _, _ = p.Get()
+	if cfg.Name != "edited" || cfg.DB.Host != "db.edited" || cfg.Port != 8080 {
+		t.Fatalf("cfg after editing the fragments = %+v", cfg)
+	}
+}
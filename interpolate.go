@@ -0,0 +1,413 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// InterpolationError reports a variable reference in a config file that could not
+// be expanded: a required variable that is not set, or a malformed reference. It
+// matches ErrInterpolation with errors.Is.
+type InterpolationError struct {
+	Path    string // config file path
+	Key     string // key path of the value holding the reference, if known
+	Line    int    // 1-based line of the reference
+	Column  int    // 1-based column of the reference
+	Var     string // variable name, empty for malformed references
+	Message string // the ${VAR:?message} text, or a description of the problem
+}
+
+func (e *InterpolationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v %s:%d:%d", ErrInterpolation, e.Path, e.Line, e.Column)
+	if e.Key != "" {
+		fmt.Fprintf(&b, ": %s", e.Key)
+	}
+	if e.Var != "" {
+		fmt.Fprintf(&b, ": %s", e.Var)
+	}
+	fmt.Fprintf(&b, ": %s", e.Message)
+	return b.String()
+}
+
+func (e *InterpolationError) Unwrap() error { return ErrInterpolation }
+
+// WithInterpolation enables or disables the expansion of environment variable
+// references in the string values of config files. It is disabled by default.
+// The supported forms are:
+//
+//	${VAR}           value of VAR, empty if unset
+//	${VAR:-default}  default if VAR is unset or empty (${VAR-default}: only if unset)
+//	${VAR:?message}  error if VAR is unset or empty (${VAR?message}: only if unset)
+//	$$               a literal $
+//
+// References are expanded after a file is decoded, so values are never parsed as
+// file syntax, and keys, comments and non-string values are left as they are. A
+// plain (unquoted) YAML scalar that is a single reference, as in `port: ${PORT}`,
+// is then typed by the YAML rules, like a value written in its place; other
+// values stay strings.
+// A config file created by WithPersistence has "$" escaped as "$$" in its string
+// values, so that they read back unchanged.
+func WithInterpolation[T any](enabled bool) Option[T] {
+	return func(m *Provider[T]) {
+		m.interpolate = enabled
+	}
+}
+
+// interpolate expands the variable references in data using lookup. Problems are
+// returned without Path and Key, with positions relative to data.
+func interpolate(data []byte, lookup func(string) (string, bool)) ([]byte, []*InterpolationError) {
+	if !bytes.ContainsRune(data, '$') {
+		return data, nil
+	}
+	var (
+		out  bytes.Buffer
+		errs []*InterpolationError
+	)
+	fail := func(off int, name, msg string) {
+		line, col := lineCol(data, int64(off))
+		errs = append(errs, &InterpolationError{Line: line, Column: col, Var: name, Message: msg})
+	}
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if c != '$' || i+1 == len(data) {
+			out.WriteByte(c)
+			continue
+		}
+		switch data[i+1] {
+		case '$':
+			out.WriteByte('$')
+			i++
+			continue
+		case '{':
+		default:
+			out.WriteByte(c)
+			continue
+		}
+
+		end := bytes.IndexByte(data[i+2:], '}')
+		if end < 0 {
+			fail(i, "", "unterminated variable reference")
+			out.Write(data[i:])
+			break
+		}
+		ref := string(data[i+2 : i+2+end])
+		value, err := expandRef(ref, lookup)
+		if err != nil {
+			var name string
+			if n := varNameLen(ref); n > 0 {
+				name = ref[:n]
+			}
+			fail(i, name, err.Error())
+		}
+		out.WriteString(value)
+		i += 2 + end
+	}
+	return out.Bytes(), errs
+}
+
+// expandRef expands the contents of one ${...} reference.
+func expandRef(ref string, lookup func(string) (string, bool)) (string, error) {
+	n := varNameLen(ref)
+	if n == 0 {
+		return "", fmt.Errorf("invalid variable reference ${%s}", ref)
+	}
+	name, op := ref[:n], ref[n:]
+	value, ok := lookup(name)
+
+	colon := strings.HasPrefix(op, ":")
+	set := ok && (!colon || value != "")
+	op = strings.TrimPrefix(op, ":")
+	switch {
+	case op == "" && !colon:
+		return value, nil
+	case strings.HasPrefix(op, "-"):
+		if set {
+			return value, nil
+		}
+		return op[1:], nil
+	case strings.HasPrefix(op, "?"):
+		if set {
+			return value, nil
+		}
+		if msg := op[1:]; msg != "" {
+			return "", errors.New(msg)
+		}
+		return "", errors.New("required variable is not set")
+	default:
+		return "", fmt.Errorf("invalid variable reference ${%s}", ref)
+	}
+}
+
+// varNameLen returns the length of the variable name at the start of s.
+func varNameLen(s string) int {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return i
+		}
+	}
+	return len(s)
+}
+
+// expandTree expands the variable references in the string values of the tree
+// decoded from the file at path, in place, and reports whether any value changed.
+// Problems are returned as *InterpolationError values joined together.
+func (f fileIO) expandTree(path string, tree interface{}) (bool, error) {
+	lookup := f.lookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	var (
+		changed bool
+		errs    []*InterpolationError
+		values  [][]interface{} // key paths of the values in errs
+		whole   [][]interface{} // key paths of the values that were one reference
+	)
+	var walk func(v interface{}, elems []interface{}) interface{}
+	walk = func(v interface{}, elems []interface{}) interface{} {
+		switch t := v.(type) {
+		case string:
+			if !strings.Contains(t, "$") || len(elems) == 0 {
+				return t
+			}
+			expanded, problems := interpolate([]byte(t), lookup)
+			for _, e := range problems {
+				e.Key = keyPath(elems)
+				errs = append(errs, e)
+				values = append(values, elems)
+			}
+			if strings.HasPrefix(t, "${") && strings.IndexByte(t, '}') == len(t)-1 {
+				whole = append(whole, elems)
+			}
+			changed = changed || string(expanded) != t
+			return string(expanded)
+		case map[string]interface{}:
+			for k, e := range t {
+				t[k] = walk(e, append(elems[:len(elems):len(elems)], k))
+			}
+		case []interface{}:
+			for i, e := range t {
+				t[i] = walk(e, append(elems[:len(elems):len(elems)], i))
+			}
+		}
+		return v
+	}
+	walk(tree, nil)
+	if len(errs) == 0 && len(whole) == 0 {
+		return changed, nil
+	}
+
+	codec, data, err := f.read(path)
+	if err != nil {
+		return false, err
+	}
+	if len(errs) == 0 {
+		// A plain YAML scalar holding only a reference is typed like a value
+		// written in its place, so that `port: ${PORT}` sets an int.
+		if _, ok := codec.(yamlCodec); ok {
+			var doc yaml.Node
+			if err := yaml.Unmarshal(data, &doc); err == nil {
+				for _, elems := range whole {
+					if n := yamlNodeAt(&doc, elems); n != nil && n.Kind == yaml.ScalarNode && n.Style == 0 {
+						setAt(tree, elems, plainScalar(valueAt(tree, elems).(string)))
+						changed = true
+					}
+				}
+			}
+		}
+		return changed, nil
+	}
+	for i, e := range errs {
+		e.Path = path
+		e.Line, e.Column = referencePosition(codec, data, values[i], e)
+	}
+	// Maps are walked in random order: report the problems in document order.
+	sort.SliceStable(errs, func(i, j int) bool {
+		a, b := errs[i], errs[j]
+		return a.Line < b.Line || a.Line == b.Line && (a.Column < b.Column || a.Column == b.Column && a.Key < b.Key)
+	})
+	joined := make([]error, len(errs))
+	for i, e := range errs {
+		joined[i] = e
+	}
+	return false, errors.Join(joined...)
+}
+
+// referencePosition returns the position in data of the reference reported by e,
+// whose Line and Column are relative to the string value at elems. The search
+// starts at the value where the format allows locating it.
+func referencePosition(codec Codec, data []byte, elems []interface{}, e *InterpolationError) (line, col int) {
+	var start int64
+	switch codec.(type) {
+	case yamlCodec:
+		start = yamlValueOffset(data, elems)
+	case jsonCodec:
+		dotted := make([]string, len(elems))
+		for i, el := range elems {
+			dotted[i] = fmt.Sprint(el)
+		}
+		start, _, _ = jsonValueAt(data, strings.Join(dotted, "."))
+	case tomlCodec:
+		var key toml.Key
+		for _, el := range elems {
+			if k, ok := el.(string); ok {
+				key = append(key, k)
+			}
+		}
+		if l, _ := tomlKeyAt(data, key); l > 0 {
+			start = offsetOf(data, l, 1)
+		}
+	}
+	ref := "${"
+	if e.Var != "" {
+		ref += e.Var
+	}
+	if i := bytes.Index(data[start:], []byte(ref)); i >= 0 {
+		return lineCol(data, start+int64(i))
+	}
+	return 0, 0
+}
+
+// yamlValueOffset returns the offset of the value at elems in a YAML document,
+// or 0 if it is not found.
+func yamlValueOffset(data []byte, elems []interface{}) int64 {
+	var doc yaml.Node
+	if yaml.Unmarshal(data, &doc) != nil {
+		return 0
+	}
+	n := yamlNodeAt(&doc, elems)
+	if n == nil {
+		return 0
+	}
+	return offsetOf(data, n.Line, n.Column)
+}
+
+// yamlNodeAt returns the node of the value at elems in doc, or nil if it is not
+// found.
+func yamlNodeAt(doc *yaml.Node, elems []interface{}) *yaml.Node {
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	n := doc.Content[0]
+	for _, el := range elems {
+		if n.Kind == yaml.AliasNode {
+			n = n.Alias
+		}
+		var next *yaml.Node
+		switch k := el.(type) {
+		case string:
+			for i := 0; n.Kind == yaml.MappingNode && i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == k {
+					next = n.Content[i+1]
+				}
+			}
+		case int:
+			if n.Kind == yaml.SequenceNode && k < len(n.Content) {
+				next = n.Content[k]
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+// offsetOf returns the offset of the 1-based line and column (in runes) in data.
+func offsetOf(data []byte, line, col int) int64 {
+	off := 0
+	for l := 1; l < line; l++ {
+		i := bytes.IndexByte(data[off:], '\n')
+		if i < 0 {
+			return int64(len(data))
+		}
+		off += i + 1
+	}
+	for c := 1; c < col && off < len(data); c++ {
+		_, size := utf8.DecodeRune(data[off:])
+		off += size
+	}
+	return int64(off)
+}
+
+// keyPath formats key path elements as in "servers[1].host".
+func keyPath(elems []interface{}) string {
+	var key string
+	for _, el := range elems {
+		switch k := el.(type) {
+		case int:
+			key += "[" + strconv.Itoa(k) + "]"
+		default:
+			key = joinKey(key, fmt.Sprint(k))
+		}
+	}
+	return key
+}
+
+// plainScalar is an expanded plain YAML scalar. It is encoded as a plain scalar
+// again, so that its type is resolved by the YAML rules when it is decoded, e.g.
+// as an int; values that cannot be plain are quoted and stay strings.
+type plainScalar string
+
+func (s plainScalar) MarshalYAML() (interface{}, error) {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: string(s)}, nil
+}
+
+// valueAt returns the value at elems in tree.
+func valueAt(tree interface{}, elems []interface{}) interface{} {
+	for _, el := range elems {
+		switch k := el.(type) {
+		case string:
+			tree = tree.(map[string]interface{})[k]
+		case int:
+			tree = tree.([]interface{})[k]
+		}
+	}
+	return tree
+}
+
+// setAt replaces the value at elems, which must not be empty, in tree.
+func setAt(tree interface{}, elems []interface{}, v interface{}) {
+	parent := valueAt(tree, elems[:len(elems)-1])
+	switch k := elems[len(elems)-1].(type) {
+	case string:
+		parent.(map[string]interface{})[k] = v
+	case int:
+		parent.([]interface{})[k] = v
+	}
+}
+
+// escapeTree escapes "$" as "$$" in the string values of tree, in place, so that
+// they read back unchanged when interpolation is enabled.
+func escapeTree(tree interface{}) interface{} {
+	switch t := tree.(type) {
+	case string:
+		return strings.ReplaceAll(t, "$", "$$")
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = escapeTree(e)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = escapeTree(e)
+		}
+	}
+	return tree
+}
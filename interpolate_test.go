@@ -0,0 +1,242 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	env := map[string]string{"HOST": "db", "EMPTY": "", "PORT": "5432"}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr []InterpolationError
+	}{
+		{name: "no references", in: "a: b\n", want: "a: b\n"},
+		{name: "plain", in: "dsn: postgres://${HOST}:${PORT}/app", want: "dsn: postgres://db:5432/app"},
+		{name: "unset is empty", in: "x: '${MISSING}'", want: "x: ''"},
+		{name: "default when unset", in: "${MISSING:-localhost}", want: "localhost"},
+		{name: "default when empty", in: "${EMPTY:-localhost}", want: "localhost"},
+		{name: "default only when unset", in: "[${EMPTY-localhost}]", want: "[]"},
+		{name: "default not used when set", in: "${HOST:-localhost}", want: "db"},
+		{name: "required set", in: "${HOST:?need host}", want: "db"},
+		{name: "escaped", in: "price: $$5 and $${HOST}", want: "price: $5 and ${HOST}"},
+		{name: "lone dollar", in: "a$b $", want: "a$b $"},
+		{
+			name:    "required unset",
+			in:      "a: 1\npassword: ${DB_PASSWORD:?set the password}\n",
+			wantErr: []InterpolationError{{Line: 2, Column: 11, Var: "DB_PASSWORD", Message: "set the password"}},
+		},
+		{
+			name:    "required empty and only-unset form",
+			in:      "${EMPTY:?} ${EMPTY?}",
+			wantErr: []InterpolationError{{Line: 1, Column: 1, Var: "EMPTY", Message: "required variable is not set"}},
+		},
+		{
+			name: "malformed references",
+			in:   "${1X} ${HOST:x}\n${OPEN",
+			wantErr: []InterpolationError{
+				{Line: 1, Column: 1, Message: "invalid variable reference ${1X}"},
+				{Line: 1, Column: 7, Var: "HOST", Message: "invalid variable reference ${HOST:x}"},
+				{Line: 2, Column: 1, Message: "unterminated variable reference"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := interpolate([]byte(tt.in), lookup)
+			if len(errs) != len(tt.wantErr) {
+				t.Fatalf("errors = %v, want %v", errs, tt.wantErr)
+			}
+			for i, e := range errs {
+				if *e != tt.wantErr[i] {
+					t.Fatalf("error %d = %+v, want %+v", i, *e, tt.wantErr[i])
+				}
+			}
+			if tt.wantErr == nil && string(got) != tt.want {
+				t.Fatalf("interpolate = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProvider_Get_Interpolation(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("CFG_NAME", "from-env")
+
+	get := func(t *testing.T, file, data string, opts ...Option[testCfg2]) (*testCfg2, error) {
+		t.Helper()
+		path := filepath.Join(td, file)
+		writeFile(t, path, data)
+		t.Setenv("MYAPP_CONFIG_PATH", path)
+		opts = append([]Option[testCfg2]{WithEnvPrefix[testCfg2]("MYAPP"), WithInterpolation[testCfg2](true)}, opts...)
+		cfg, _, _, err := New[testCfg2](opts...).Get()
+		return cfg, err
+	}
+
+	t.Run("string values", func(t *testing.T) {
+		for file, data := range map[string]string{
+			"c.yaml": "name: ${CFG_NAME}-${CFG_SUFFIX:-x}\ncount: 2\n",
+			"c.json": `{"name": "${CFG_NAME}-${CFG_SUFFIX:-x}", "count": 2}`,
+			"c.toml": "name = \"${CFG_NAME}-${CFG_SUFFIX:-x}\"\ncount = 2\n",
+		} {
+			cfg, err := get(t, file, data)
+			if err != nil {
+				t.Fatalf("%s: Get: %v", file, err)
+			}
+			if cfg.Name != "from-env-x" || cfg.Count != 2 {
+				t.Fatalf("%s: unexpected cfg: %+v", file, cfg)
+			}
+		}
+	})
+
+	t.Run("plain yaml references are typed", func(t *testing.T) {
+		t.Setenv("CFG_COUNT", "7")
+		t.Setenv("CFG_NUM", "0123")
+		cfg, err := get(t, "typed.yaml", "name: ${CFG_NUM}\ncount: ${CFG_COUNT}\ndur: ${CFG_DUR:-2s}\n")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if cfg.Name != "0123" || cfg.Count != 7 || cfg.Dur.String() != "2s" {
+			t.Fatalf("unexpected cfg: %+v", cfg)
+		}
+	})
+
+	t.Run("quoted references stay strings", func(t *testing.T) {
+		t.Setenv("CFG_COUNT", "7")
+		for _, tc := range []struct {
+			file, data string
+			line, col  int
+		}{
+			{"quoted.yaml", "name: a\ncount: \"${CFG_COUNT}\"\n", 2, 8},
+			{"quoted.json", "{\"name\": \"a\",\n \"count\": \"${CFG_COUNT}\"}", 2, 11},
+		} {
+			_, err := get(t, tc.file, tc.data)
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("%s: error = %v, want a *ParseError", tc.file, err)
+			}
+			if pe.Path != filepath.Join(td, tc.file) || pe.Key != "count" || pe.Line != tc.line || pe.Column != tc.col {
+				t.Fatalf("%s: unexpected error: %+v", tc.file, pe)
+			}
+		}
+	})
+
+	t.Run("values are not parsed as file syntax", func(t *testing.T) {
+		for _, value := range []string{"abc #def", "x\nadmin: true", "*secret", "&anchor", "a: b", "{\"c\": 1}", "'q\""} {
+			t.Setenv("CFG_VALUE", value)
+			for _, file := range []string{"v.yaml", "v.json", "v.toml"} {
+				data := "name: ${CFG_VALUE}\n"
+				switch filepath.Ext(file) {
+				case ".json":
+					data = `{"name": "${CFG_VALUE}"}`
+				case ".toml":
+					data = "name = \"${CFG_VALUE}\"\n"
+				}
+				cfg, err := get(t, file, data)
+				if err != nil {
+					t.Fatalf("%s %q: Get: %v", file, value, err)
+				}
+				if cfg.Name != value || cfg.Count != 0 {
+					t.Fatalf("%s %q: unexpected cfg: %+v", file, value, cfg)
+				}
+			}
+		}
+	})
+
+	t.Run("keys and comments are left as they are", func(t *testing.T) {
+		cfg, err := get(t, "k.yaml", "# ${CFG_MISSING:?not expanded}\nname: a\n${CFG_NAME}: b\n")
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if cfg.Name != "a" {
+			t.Fatalf("Name = %q, want a", cfg.Name)
+		}
+	})
+
+	t.Run("missing required variable names file and key", func(t *testing.T) {
+		_, err := get(t, "req.yaml", "count: 1\nname: \"user-${CFG_USER:?user is required}\"\n")
+		if !errors.Is(err, ErrInterpolation) {
+			t.Fatalf("error = %v, want ErrInterpolation", err)
+		}
+		var ie *InterpolationError
+		if !errors.As(err, &ie) {
+			t.Fatalf("error %T is not an *InterpolationError", err)
+		}
+		path := filepath.Join(td, "req.yaml")
+		if ie.Path != path || ie.Key != "name" || ie.Var != "CFG_USER" || ie.Line != 2 || ie.Column != 13 {
+			t.Fatalf("unexpected error: %+v", ie)
+		}
+		if want := "interpolate config file " + path + ":2:13: name: CFG_USER: user is required"; err.Error() != want {
+			t.Fatalf("Error() = %q, want %q", err.Error(), want)
+		}
+	})
+
+	t.Run("missing required variable in json and toml", func(t *testing.T) {
+		for _, tc := range []struct {
+			file, data string
+			col        int
+		}{
+			{"req.json", "{\"count\": 1,\n \"name\": \"${CFG_USER:?}\"}", 11},
+			{"req.toml", "count = 1\nname = \"${CFG_USER:?}\"\n", 9},
+		} {
+			_, err := get(t, tc.file, tc.data)
+			var ie *InterpolationError
+			if !errors.As(err, &ie) {
+				t.Fatalf("%s: error = %v, want an *InterpolationError", tc.file, err)
+			}
+			if ie.Key != "name" || ie.Line != 2 || ie.Column != tc.col {
+				t.Fatalf("%s: unexpected error: %+v", tc.file, ie)
+			}
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		path := filepath.Join(td, "off.yaml")
+		writeFile(t, path, "name: ${CFG_NAME:?x} $$\n")
+		t.Setenv("MYAPP_CONFIG_PATH", path)
+		cfg, _, _, err := New[testCfg2](WithEnvPrefix[testCfg2]("MYAPP")).Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if cfg.Name != "${CFG_NAME:?x} $$" {
+			t.Fatalf("Name = %q, want the raw text", cfg.Name)
+		}
+	})
+}
+
+func TestProvider_Get_InterpolationPersistence(t *testing.T) {
+	for _, file := range []string{"config.yaml", "config.json", "config.toml"} {
+		t.Run(file, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+			t.Setenv("name", "expanded")
+			p := New[testCfg2](
+				WithInterpolation[testCfg2](true),
+				WithPersistence[testCfg2]("app"),
+				WithFileName[testCfg2](file),
+				WithDefaultFn(func() *testCfg2 { return &testCfg2{Name: "Hello ${name} pa$$word"} }),
+			)
+			for i := 0; i < 2; i++ {
+				cfg, _, _, err := p.Get()
+				if err != nil {
+					t.Fatalf("Get %d: %v", i, err)
+				}
+				if cfg.Name != "Hello ${name} pa$$word" {
+					t.Fatalf("Get %d: Name = %q, want the default", i, cfg.Name)
+				}
+				if err := p.Reload(context.Background()); err != nil {
+					t.Fatalf("Reload: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -1,7 +1,18 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -87,7 +98,16 @@ func TestLoadFromEnv_AllBranches_WithPrefix(t *testing.T) {
 		WithEnvPrefix[envCfg](prefix),
 		WithDefaultFn(func() *envCfg { return &envCfg{} }),
 	)
-	p.loadFromEnv(&c)
+	err := p.loadFromEnv(&c)
+
+	// The negative uint is the only invalid value and must be reported.
+	var ee *EnvError
+	if !errors.Is(err, ErrEnv) || !errors.As(err, &ee) {
+		t.Fatalf("expected ErrEnv/*EnvError, got %v", err)
+	}
+	if ee.Var != prefix+"_INNER_NEG_U" || ee.Field != "Inner.NegU" || ee.Value != "-3" {
+		t.Fatalf("unexpected EnvError: %+v", ee)
+	}
 
 	// Top-level, default SCREAMING_SNAKE
 	if c.S != "top" {
@@ -219,11 +239,63 @@ func TestLoadFromEnv_ParseFailures_DoNotAllocate(t *testing.T) {
 		WithEnvPrefix[envCfg]("APP"),
 		WithDefaultFn(func() *envCfg { return &envCfg{} }),
 	)
-	p.loadFromEnv(&c)
+	err := p.loadFromEnv(&c)
 
 	if c.PtrBool != nil || c.PtrInt != nil || c.PtrDur != nil {
 		t.Fatalf("invalid parse should not allocate pointer fields")
 	}
+
+	// Every invalid value is reported, not just the first one.
+	for _, name := range []string{"APP_PBOOL", "APP_PINT", "APP_PDUR"} {
+		if err == nil || !strings.Contains(err.Error(), name) {
+			t.Fatalf("error %v does not mention %s", err, name)
+		}
+	}
+}
+
+func TestLoadFromEnv_Lenient_IgnoresParseFailures(t *testing.T) {
+	t.Setenv("APP_PINT", "NaN")
+	t.Setenv("APP_INNER_INT", "80a")
+
+	var c envCfg
+	p := New[envCfg](
+		WithEnvPrefix[envCfg]("APP"),
+		WithLenientEnv[envCfg](),
+	)
+	if err := p.loadFromEnv(&c); err != nil {
+		t.Fatalf("lenient mode: unexpected error %v", err)
+	}
+	if c.PtrInt != nil || c.Inner.I != 0 {
+		t.Fatalf("invalid values must not be applied: %+v", c)
+	}
+}
+
+func TestProvider_Get_EnvError(t *testing.T) {
+	t.Setenv("MYAPP_CONFIG_PATH", "")
+	t.Setenv("MYAPP_COUNT", "80a")
+	t.Setenv("MYAPP_DUR", "soon")
+
+	p := New[testCfg2](
+		WithEnvPrefix[testCfg2]("MYAPP"),
+		WithDefaultFn[testCfg2](defFn),
+	)
+	_, _, _, err := p.Get()
+	if !errors.Is(err, ErrEnv) {
+		t.Fatalf("Get error = %v, want ErrEnv", err)
+	}
+
+	var got []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var ee *EnvError
+		if !errors.As(e, &ee) {
+			t.Fatalf("expected *EnvError, got %T", e)
+		}
+		got = append(got, ee.Var+"/"+ee.Field+"/"+ee.Type.String())
+	}
+	want := []string{"MYAPP_COUNT/Count/int", "MYAPP_DUR/Dur/time.Duration"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("EnvErrors = %v, want %v", got, want)
+	}
 }
 
 // Ensure SCREAMING_SNAKE fallback naming is correct for mixed-case with digits.
@@ -258,21 +330,42 @@ func TestBuildEnvName(t *testing.T) {
 	}
 }
 
-// Quick sanity check for getBool/getInt/getDuration (via env)
-func TestPrimitiveParsers(t *testing.T) {
-	t.Setenv("X_BOOL", "true")
-	t.Setenv("X_INT", "123")
-	t.Setenv("X_DUR", "2s")
-	if b, ok := getBool("X_BOOL"); !ok || !b {
-		t.Fatalf("getBool failed")
+// Quick sanity check for setScalar parsing of bool/int/duration/uint
+func TestSetScalar(t *testing.T) {
+	l := &envLoader{}
+	var (
+		b bool
+		n int
+		d time.Duration
+		u uint
+	)
+	if err := l.setScalar(reflect.ValueOf(&b).Elem(), "true"); err != nil || !b {
+		t.Fatalf("bool: %v %v", b, err)
+	}
+	if err := l.setScalar(reflect.ValueOf(&n).Elem(), " 123 "); err != nil || n != 123 {
+		t.Fatalf("int: %v %v", n, err)
+	}
+	if err := l.setScalar(reflect.ValueOf(&d).Elem(), "2s"); err != nil || d != 2*time.Second {
+		t.Fatalf("duration: %v %v", d, err)
 	}
-	if n, ok := getInt("X_INT"); !ok || n != 123 {
-		t.Fatalf("getInt failed: %v %v", n, ok)
+	if err := l.setScalar(reflect.ValueOf(&u).Elem(), "7"); err != nil || u != 7 {
+		t.Fatalf("uint: %v %v", u, err)
 	}
-	if d, ok := getDuration("X_DUR"); !ok || d != 2*time.Second {
-		t.Fatalf("getDuration failed: %v %v", d, ok)
+
+	// Parse failures return an error and leave the value untouched.
+	for _, tc := range []struct {
+		v   any
+		raw string
+	}{
+		{&b, "yes"}, {&n, "80a"}, {&d, "soon"}, {&u, "-1"},
+	} {
+		if err := l.setScalar(reflect.ValueOf(tc.v).Elem(), tc.raw); err == nil {
+			t.Fatalf("setScalar(%T, %q): expected error", tc.v, tc.raw)
+		}
+	}
+	if !b || n != 123 || d != 2*time.Second || u != 7 {
+		t.Fatalf("failed parse modified values: %v %v %v %v", b, n, d, u)
 	}
-	// Negative int for unsigned path is handled in applyEnv; parsers just return the value.
 }
 
 // Guard that loadFromEnv does not explode on non-struct pointers accidentally passed
@@ -284,3 +377,480 @@ func TestLoadFromEnv_NonStructPointer_NoPanic(t *testing.T) {
 	p.loadFromEnv(&z) // should not panic
 	_ = z
 }
+
+type server struct {
+	Host string `env:"HOST"`
+	Port int    `env:"PORT"`
+}
+
+type listCfg struct {
+	Hosts    []string        `env:"HOSTS"`
+	Ports    []int           `env:"PORTS" envSeparator:";"`
+	Timeouts []time.Duration `env:"TIMEOUTS"`
+	Flags    [3]bool         `env:"FLAGS"`
+	PtrIDs   []*uint         `env:"IDS"`
+	Empty    []string        `env:"EMPTY"`
+	Servers  []server        `env:"SERVERS"`
+	PServers []*server       `env:"PSERVERS"`
+	Pair     [2]server       `env:"PAIR"`
+}
+
+func TestLoadFromEnv_Slices(t *testing.T) {
+	t.Setenv("APP_HOSTS", "a.example, b.example ,c.example")
+	t.Setenv("APP_PORTS", "80;443")
+	t.Setenv("APP_TIMEOUTS", "1s,250ms")
+	t.Setenv("APP_FLAGS", "true,false")
+	t.Setenv("APP_IDS", "4,2")
+	t.Setenv("APP_EMPTY", "")
+	t.Setenv("APP_SERVERS_0_PORT", "8080")
+	t.Setenv("APP_SERVERS_1_HOST", "second")
+	t.Setenv("APP_SERVERS_2_HOST", "third")
+	t.Setenv("APP_PSERVERS_0_HOST", "pfirst")
+	t.Setenv("APP_PAIR_1_HOST", "second")
+
+	// Elements already present (e.g. from a file) are updated in place.
+	c := listCfg{Servers: []server{{Host: "first", Port: 1}}}
+	p := New[listCfg](WithEnvPrefix[listCfg]("APP"))
+	if err := p.loadFromEnv(&c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(c.Hosts, []string{"a.example", "b.example", "c.example"}) {
+		t.Fatalf("Hosts = %q", c.Hosts)
+	}
+	if !reflect.DeepEqual(c.Ports, []int{80, 443}) {
+		t.Fatalf("Ports = %v", c.Ports)
+	}
+	if !reflect.DeepEqual(c.Timeouts, []time.Duration{time.Second, 250 * time.Millisecond}) {
+		t.Fatalf("Timeouts = %v", c.Timeouts)
+	}
+	if c.Flags != [3]bool{true, false, false} {
+		t.Fatalf("Flags = %v", c.Flags)
+	}
+	if len(c.PtrIDs) != 2 || *c.PtrIDs[0] != 4 || *c.PtrIDs[1] != 2 {
+		t.Fatalf("PtrIDs = %v", c.PtrIDs)
+	}
+	if c.Empty == nil || len(c.Empty) != 0 {
+		t.Fatalf("Empty = %#v, want empty non-nil slice", c.Empty)
+	}
+	wantServers := []server{{Host: "first", Port: 8080}, {Host: "second"}, {Host: "third"}}
+	if !reflect.DeepEqual(c.Servers, wantServers) {
+		t.Fatalf("Servers = %+v, want %+v", c.Servers, wantServers)
+	}
+	if len(c.PServers) != 1 || c.PServers[0].Host != "pfirst" {
+		t.Fatalf("PServers = %+v", c.PServers)
+	}
+	if c.Pair[1].Host != "second" || c.Pair[0].Host != "" {
+		t.Fatalf("Pair = %+v", c.Pair)
+	}
+}
+
+func TestLoadFromEnv_Slices_Errors(t *testing.T) {
+	t.Setenv("APP_PORTS", "80;http")
+	t.Setenv("APP_FLAGS", "true,true,true,true")
+	t.Setenv("APP_PAIR_2_HOST", "out-of-range")
+	t.Setenv("APP_SERVERS_0_PORT", "x")
+	t.Setenv("APP_SERVERS_2_HOST", "gap")
+	t.Setenv("APP_PSERVERS_4611686018427387904_HOST", "huge")
+
+	var c listCfg
+	p := New[listCfg](WithEnvPrefix[listCfg]("APP"))
+	err := p.loadFromEnv(&c)
+	if !errors.Is(err, ErrEnv) {
+		t.Fatalf("expected ErrEnv, got %v", err)
+	}
+	for _, want := range []string{
+		"APP_PORTS", "element 1",
+		"APP_FLAGS", "exceed array length 3",
+		"APP_PAIR_2", "Pair[2]", "out of range",
+		"APP_SERVERS_0_PORT", "Servers[0].Port",
+		"APP_SERVERS_2", "Servers[2]", "index 2 out of range for length 1",
+		"APP_PSERVERS_4611686018427387904", "out of range for length 0",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %q does not contain %q", err, want)
+		}
+	}
+}
+
+type mapCfg struct {
+	Labels    map[string]string `env:"LABELS"`
+	Limits    map[string]int    `env:"LIMITS" envSeparator:";" envKeyValueSeparator:":"`
+	ByCode    map[int]bool      `env:"BY_CODE"`
+	Databases map[string]server `env:"DATABASES"`
+	Replicas  map[string]*server
+	Untouched map[string]string `env:"UNTOUCHED"`
+}
+
+func TestLoadFromEnv_Maps(t *testing.T) {
+	t.Setenv("APP_LABELS", "team=core, tier = backend")
+	t.Setenv("APP_LABELS_TIER", "frontend") // discovery wins over inline
+	t.Setenv("APP_LABELS_OWNER", "ops")
+	t.Setenv("APP_LIMITS", "cpu:2;mem:512")
+	t.Setenv("APP_BY_CODE_404", "true")
+	t.Setenv("APP_DATABASES_PRIMARY_HOST", "db1")
+	t.Setenv("APP_DATABASES_PRIMARY_PORT", "5432")
+	t.Setenv("APP_DATABASES_READ_ONLY_HOST", "db2")
+	t.Setenv("APP_REPLICAS_EU_PORT", "6000")
+
+	// Existing entries (e.g. from a file) are matched case-insensitively and merged.
+	c := mapCfg{Databases: map[string]server{"Primary": {Host: "old", Port: 1}}}
+	p := New[mapCfg](WithEnvPrefix[mapCfg]("APP"))
+	if err := p.loadFromEnv(&c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantLabels := map[string]string{"team": "core", "tier": "frontend", "owner": "ops"}
+	if !reflect.DeepEqual(c.Labels, wantLabels) {
+		t.Fatalf("Labels = %v, want %v", c.Labels, wantLabels)
+	}
+	if !reflect.DeepEqual(c.Limits, map[string]int{"cpu": 2, "mem": 512}) {
+		t.Fatalf("Limits = %v", c.Limits)
+	}
+	if !reflect.DeepEqual(c.ByCode, map[int]bool{404: true}) {
+		t.Fatalf("ByCode = %v", c.ByCode)
+	}
+	wantDBs := map[string]server{
+		"Primary":   {Host: "db1", Port: 5432},
+		"read_only": {Host: "db2"},
+	}
+	if !reflect.DeepEqual(c.Databases, wantDBs) {
+		t.Fatalf("Databases = %+v, want %+v", c.Databases, wantDBs)
+	}
+	if r := c.Replicas["eu"]; r == nil || r.Port != 6000 {
+		t.Fatalf("Replicas = %+v", c.Replicas)
+	}
+	if c.Untouched != nil {
+		t.Fatalf("Untouched should stay nil when no env vars are set")
+	}
+}
+
+func TestLoadFromEnv_Maps_Errors(t *testing.T) {
+	t.Setenv("APP_LABELS", "team")
+	t.Setenv("APP_LIMITS_CPU", "lots")
+	t.Setenv("APP_BY_CODE_NOTFOUND", "true")
+
+	var c mapCfg
+	p := New[mapCfg](WithEnvPrefix[mapCfg]("APP"))
+	err := p.loadFromEnv(&c)
+	if !errors.Is(err, ErrEnv) {
+		t.Fatalf("expected ErrEnv, got %v", err)
+	}
+	for _, want := range []string{
+		"APP_LABELS=", `missing "="`,
+		"APP_LIMITS_CPU", "Limits[CPU]",
+		"APP_BY_CODE_NOTFOUND", `key "NOTFOUND"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %q does not contain %q", err, want)
+		}
+	}
+}
+
+type numCfg struct {
+	Ratio   float64         `env:"RATIO"`
+	Rate    float32         `env:"RATE"`
+	PRate   *float32        `env:"PRATE"`
+	Weights []float64       `env:"WEIGHTS"`
+	Signal  complex128      `env:"SIGNAL"`
+	Small   int8            `env:"SMALL"`
+	Mid     int32           `env:"MID"`
+	Byte    uint8           `env:"BYTE"`
+	Word    uint16          `env:"WORD"`
+	Big     *big.Int        `env:"BIG"`
+	BigVal  big.Int         `env:"BIG_VAL"`
+	Precise *big.Float      `env:"PRECISE"`
+	Limits  map[string]int8 `env:"LIMITS"`
+}
+
+func TestLoadFromEnv_Numbers(t *testing.T) {
+	t.Setenv("APP_RATIO", "0.25")
+	t.Setenv("APP_RATE", "1.5e3")
+	t.Setenv("APP_PRATE", "0.01")
+	t.Setenv("APP_WEIGHTS", "0.1, 2, -3.5")
+	t.Setenv("APP_SIGNAL", "1+2i")
+	t.Setenv("APP_SMALL", "-128")
+	t.Setenv("APP_MID", "2147483647")
+	t.Setenv("APP_BYTE", "255")
+	t.Setenv("APP_WORD", "65535")
+	t.Setenv("APP_BIG", "123456789012345678901234567890")
+	t.Setenv("APP_BIG_VAL", "0xff")
+	t.Setenv("APP_PRECISE", "3.14159265358979323846264338327950288")
+	t.Setenv("APP_LIMITS_CPU", "4")
+
+	var c numCfg
+	p := New[numCfg](WithEnvPrefix[numCfg]("APP"))
+	if err := p.loadFromEnv(&c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if c.Ratio != 0.25 || c.Rate != 1500 || c.PRate == nil || *c.PRate != float32(0.01) {
+		t.Fatalf("floats: %v %v %v", c.Ratio, c.Rate, c.PRate)
+	}
+	if !reflect.DeepEqual(c.Weights, []float64{0.1, 2, -3.5}) {
+		t.Fatalf("Weights = %v", c.Weights)
+	}
+	if c.Signal != complex(1, 2) {
+		t.Fatalf("Signal = %v", c.Signal)
+	}
+	if c.Small != -128 || c.Mid != 2147483647 || c.Byte != 255 || c.Word != 65535 {
+		t.Fatalf("sized ints: %v %v %v %v", c.Small, c.Mid, c.Byte, c.Word)
+	}
+	if c.Big == nil || c.Big.String() != "123456789012345678901234567890" {
+		t.Fatalf("Big = %v", c.Big)
+	}
+	if c.BigVal.Int64() != 255 {
+		t.Fatalf("BigVal = %v", &c.BigVal)
+	}
+	if c.Precise == nil || c.Precise.Text('f', 35) != "3.14159265358979323846264338327950288" {
+		t.Fatalf("Precise = %v", c.Precise.Text('f', 35))
+	}
+	if c.Limits["cpu"] != 4 {
+		t.Fatalf("Limits = %v", c.Limits)
+	}
+}
+
+func TestLoadFromEnv_Numbers_OutOfRange(t *testing.T) {
+	for name, raw := range map[string]string{
+		"APP_RATE":    "1e40", // overflows float32
+		"APP_SMALL":   "128",
+		"APP_MID":     "2147483648",
+		"APP_BYTE":    "256",
+		"APP_WORD":    "-1",
+		"APP_RATIO":   "one",
+		"APP_SIGNAL":  "1+",
+		"APP_BIG":     "12ab",
+		"APP_PRECISE": "pi",
+		"APP_WEIGHTS": "1,x",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, raw)
+			var c numCfg
+			p := New[numCfg](WithEnvPrefix[numCfg]("APP"))
+			err := p.loadFromEnv(&c)
+			var ee *EnvError
+			if !errors.As(err, &ee) || ee.Var != name {
+				t.Fatalf("expected *EnvError for %s, got %v", name, err)
+			}
+			if !reflect.DeepEqual(c, numCfg{}) {
+				t.Fatalf("out-of-range value must not be applied: %+v", c)
+			}
+		})
+	}
+}
+
+// color is a custom enum implementing encoding.TextUnmarshaler.
+type color int
+
+func (c *color) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "red":
+		*c = 1
+	case "green":
+		*c = 2
+	default:
+		return fmt.Errorf("unknown color %q", b)
+	}
+	return nil
+}
+
+// window implements json.Unmarshaler only.
+type window struct {
+	From, To int
+}
+
+func (w *window) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	_, err := fmt.Sscanf(s, "%d-%d", &w.From, &w.To)
+	return err
+}
+
+type textCfg struct {
+	IP       net.IP           `env:"IP"`
+	Allow    []net.IP         `env:"ALLOW"`
+	Since    time.Time        `env:"SINCE"`
+	Level    slog.Level       `env:"LEVEL"`
+	Color    color            `env:"COLOR"`
+	PColor   *color           `env:"PCOLOR"`
+	Window   window           `env:"WINDOW"`
+	Endpoint *url.URL         `env:"ENDPOINT"`
+	Mirrors  []url.URL        `env:"MIRRORS"`
+	Colors   map[string]color `env:"COLORS"`
+}
+
+func parseURL(s string) (any, error) { return url.Parse(s) }
+
+func TestLoadFromEnv_Unmarshalers(t *testing.T) {
+	t.Setenv("APP_IP", "10.0.0.1")
+	t.Setenv("APP_ALLOW", "127.0.0.1, ::1")
+	t.Setenv("APP_SINCE", "2024-05-01T10:00:00Z")
+	t.Setenv("APP_LEVEL", "warn")
+	t.Setenv("APP_COLOR", "green")
+	t.Setenv("APP_PCOLOR", "red")
+	t.Setenv("APP_WINDOW", "9-17")
+	t.Setenv("APP_ENDPOINT", "https://api.example.com/v1")
+	t.Setenv("APP_MIRRORS", "https://a.example,https://b.example")
+	t.Setenv("APP_COLORS_SKY", "green")
+
+	var c textCfg
+	p := New[textCfg](
+		WithEnvPrefix[textCfg]("APP"),
+		WithDecoder[textCfg](reflect.TypeOf(url.URL{}), parseURL),
+	)
+	if err := p.loadFromEnv(&c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !c.IP.Equal(net.ParseIP("10.0.0.1")) {
+		t.Fatalf("IP = %v", c.IP)
+	}
+	if len(c.Allow) != 2 || !c.Allow[1].Equal(net.IPv6loopback) {
+		t.Fatalf("Allow = %v", c.Allow)
+	}
+	if !c.Since.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Since = %v", c.Since)
+	}
+	if c.Level != slog.LevelWarn {
+		t.Fatalf("Level = %v", c.Level)
+	}
+	if c.Color != 2 || c.PColor == nil || *c.PColor != 1 {
+		t.Fatalf("Color = %v, PColor = %v", c.Color, c.PColor)
+	}
+	if c.Window != (window{From: 9, To: 17}) {
+		t.Fatalf("Window = %+v", c.Window)
+	}
+	if c.Endpoint == nil || c.Endpoint.Host != "api.example.com" {
+		t.Fatalf("Endpoint = %v", c.Endpoint)
+	}
+	if len(c.Mirrors) != 2 || c.Mirrors[1].Host != "b.example" {
+		t.Fatalf("Mirrors = %v", c.Mirrors)
+	}
+	if c.Colors["sky"] != 2 {
+		t.Fatalf("Colors = %v", c.Colors)
+	}
+}
+
+func TestLoadFromEnv_Unmarshalers_Errors(t *testing.T) {
+	t.Setenv("APP_IP", "not-an-ip")
+	t.Setenv("APP_COLOR", "purple")
+	t.Setenv("APP_PCOLOR", "purple")
+	t.Setenv("APP_ENDPOINT", "https://ok.example")
+
+	var c textCfg
+	p := New[textCfg](
+		WithEnvPrefix[textCfg]("APP"),
+		// Decoder returning a value of the wrong type.
+		WithDecoder[textCfg](reflect.TypeOf(url.URL{}), func(string) (any, error) { return 42, nil }),
+	)
+	err := p.loadFromEnv(&c)
+	for _, want := range []string{"APP_IP", `unknown color "purple"`, "APP_PCOLOR", "APP_ENDPOINT", "returned int"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %v does not contain %q", err, want)
+		}
+	}
+	if c.IP != nil || c.Color != 0 || c.PColor != nil || c.Endpoint != nil {
+		t.Fatalf("failed values must not be applied: %+v", c)
+	}
+}
+
+type secretCfg struct {
+	DB struct {
+		Password string `env:"PASSWORD"`
+		Port     int    `env:"PORT"`
+	} `env:"DB"`
+	Tokens   []string          `env:"TOKENS"`
+	Labels   map[string]string `env:"LABELS"`
+	Cert     string            `env:"CERT"`
+	CertFile string            `env:"CERT_FILE"` // keeps its own variable
+}
+
+func TestLoadFromEnv_FileSuffix(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	secret := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		writeFile(t, path, data)
+		return path
+	}
+	env := map[string]string{
+		"APP_DB_PASSWORD_FILE": secret("db_password", "s3cret\r\n"),
+		"APP_DB_PORT_FILE":     secret("db_port", "5432\n"),
+		"APP_TOKENS_FILE":      secret("tokens", "a,b"),
+		"APP_LABELS_FILE":      secret("labels", "team=core\n"),
+		"APP_CERT_FILE":        "cert.pem",
+	}
+	environ := func() []string {
+		var list []string
+		for name, value := range env {
+			list = append(list, name+"="+value)
+		}
+		return list
+	}
+
+	// Secrets are read from the OS file system, not from the WithFS one, even
+	// if it holds a file at the same path.
+	mem := &MemFS{}
+	decoy := fsName(env["APP_DB_PASSWORD_FILE"])
+	if err := mem.MkdirAll(path.Dir(decoy), 0o700); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := mem.WriteFile(decoy, []byte("decoy"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	var c secretCfg
+	p := New[secretCfg](WithEnvPrefix[secretCfg]("APP"), WithEnviron[secretCfg](environ), WithFS[secretCfg](mem))
+	if err := p.loadFromEnv(&c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.DB.Password != "s3cret" || c.DB.Port != 5432 || c.Cert != "" || c.CertFile != "cert.pem" {
+		t.Fatalf("cfg = %+v", c)
+	}
+	if !reflect.DeepEqual(c.Tokens, []string{"a", "b"}) || !reflect.DeepEqual(c.Labels, map[string]string{"team": "core"}) {
+		t.Fatalf("Tokens = %v, Labels = %v", c.Tokens, c.Labels)
+	}
+}
+
+func TestLoadFromEnv_FileSuffix_Errors(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	password := filepath.Join(dir, "password")
+	port := filepath.Join(dir, "port")
+	missing := filepath.Join(dir, "missing")
+	writeFile(t, password, "s3cret\n")
+	writeFile(t, port, "eighty\n")
+	env := map[string]string{
+		"APP_DB_PASSWORD":      "plain",
+		"APP_DB_PASSWORD_FILE": password,
+		"APP_DB_PORT_FILE":     port,
+		"APP_TOKENS_FILE":      missing,
+	}
+
+	var c secretCfg
+	p := New[secretCfg](WithEnvPrefix[secretCfg]("APP"), WithLookupEnv[secretCfg](mapLookup(env)))
+	err := p.loadFromEnv(&c)
+	if !errors.Is(err, ErrEnv) {
+		t.Fatalf("expected ErrEnv, got %v", err)
+	}
+	for _, want := range []string{
+		fmt.Sprintf("APP_DB_PASSWORD_FILE=%q", password), "APP_DB_PASSWORD is also set",
+		fmt.Sprintf("APP_DB_PORT_FILE=%q", port), "DB.Port", `parsing "eighty"`,
+		fmt.Sprintf("APP_TOKENS_FILE=%q", missing),
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("error %q does not contain %q", err, want)
+		}
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("error %q does not match os.ErrNotExist", err)
+	}
+	if strings.Contains(err.Error(), "s3cret") {
+		t.Fatalf("error %q contains the file content", err)
+	}
+	if c.DB.Password != "" {
+		t.Fatalf("Password = %q, want it unset when both variables are set", c.DB.Password)
+	}
+}
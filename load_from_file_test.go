@@ -10,8 +10,8 @@ import (
 
 // sample config struct for (de)serialization
 type sample struct {
-	Name  string `json:"name" yaml:"name"`
-	Count int    `json:"count" yaml:"count"`
+	Name  string `json:"name" yaml:"name" toml:"name"`
+	Count int    `json:"count" yaml:"count" toml:"count"`
 }
 
 func TestLoadFromFile(t *testing.T) {
@@ -32,7 +32,9 @@ func TestLoadFromFile(t *testing.T) {
 	yamlBadPath := write(t, "bad.yaml", "name: [unclosed\n") // invalid YAML
 	jsonOKPath := write(t, "good.json", `{"name":"carol","count":3}`)
 	jsonBadPath := write(t, "bad.json", `{"name":"dave","count":,}`) // invalid JSON
-	txtPath := write(t, "notes.txt", "just text")                    // unsupported ext
+	tomlOKPath := write(t, "good.toml", "name = \"erin\"\ncount = 5\n")
+	tomlBadPath := write(t, "bad.toml", "name = \"erin\n") // invalid TOML
+	txtPath := write(t, "notes.txt", "just text")          // unsupported ext
 
 	nonexistentYAML := filepath.Join(td, "missing.yaml") // doesn't exist
 	noExtPath := write(t, "config", "name: x\n")         // no extension -> unsupported
@@ -96,6 +98,17 @@ func TestLoadFromFile(t *testing.T) {
 			wantErr: true,
 			errIs:   ErrParse,
 		},
+		{
+			name: "TOML success",
+			path: tomlOKPath,
+			want: &sample{Name: "erin", Count: 5},
+		},
+		{
+			name:    "TOML parse error",
+			path:    tomlBadPath,
+			wantErr: true,
+			errIs:   ErrParse,
+		},
 	}
 
 	for _, tt := range tests {
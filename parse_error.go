@@ -0,0 +1,260 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ParseError reports a config file that could not be decoded, with the position
+// of the problem when the decoder provides one. It matches ErrParse with
+// errors.Is, and the decoder error with errors.Is/errors.As.
+//
+// Codecs may return a *ParseError with Line, Column and Key set; Path and Snippet
+// are filled in by the Provider.
+type ParseError struct {
+	Path    string // config file path
+	Line    int    // 1-based line of the problem, 0 if unknown
+	Column  int    // 1-based column of the problem, 0 if unknown
+	Key     string // key path of the offending value, e.g. "server.port", if known
+	Snippet string // source line with a caret under Column, if Line is known
+	Err     error  // decoder error
+}
+
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v %s", ErrParse, e.Path)
+	if e.Line > 0 {
+		fmt.Fprintf(&b, ":%d", e.Line)
+		if e.Column > 0 {
+			fmt.Fprintf(&b, ":%d", e.Column)
+		}
+	}
+	if e.Key != "" {
+		fmt.Fprintf(&b, ": %s", e.Key)
+	}
+	fmt.Fprintf(&b, ": %v", e.Err)
+	return b.String()
+}
+
+func (e *ParseError) Unwrap() []error { return []error{ErrParse, e.Err} }
+
+// newParseError builds a *ParseError for err, returned when decoding data read
+// from path, locating the problem for the errors of the built-in decoders.
+func newParseError(path string, data []byte, err error) *ParseError {
+	pe := &ParseError{Path: path, Err: err}
+
+	var (
+		custom    *ParseError
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		yamlErr   *yaml.TypeError
+		tomlErr   toml.ParseError
+	)
+	switch {
+	case errors.As(err, &custom):
+		pe.Line, pe.Column, pe.Key, pe.Err = custom.Line, custom.Column, custom.Key, custom.Err
+	case errors.As(err, &syntaxErr):
+		// Offset counts the bytes read, including the offending one.
+		pe.Line, pe.Column = lineCol(data, syntaxErr.Offset-1)
+	case errors.As(err, &typeErr):
+		pe.Line, pe.Column = lineCol(data, typeErr.Offset)
+		if off, key, ok := jsonValueAt(data, typeErr.Field); ok {
+			pe.Line, pe.Column = lineCol(data, off)
+			pe.Key = key
+		}
+	case errors.As(err, &tomlErr):
+		pe.Line, pe.Column, pe.Key = tomlErr.Position.Line, tomlErr.Position.Col, tomlErr.LastKey
+		if pe.Column == 0 && tomlErr.Position.Start > 0 {
+			pe.Line, pe.Column = lineCol(data, int64(tomlErr.Position.Start))
+		}
+	case errors.As(err, &yamlErr) && len(yamlErr.Errors) > 0:
+		pe.Line = errorLine(yamlErr.Errors[0])
+		pe.Key, pe.Column = yamlValueAt(data, pe.Line)
+	default:
+		// yaml syntax errors and toml decoding errors only carry the line
+		// in their message.
+		pe.Line = errorLine(err.Error())
+		if m := tomlKeyRe.FindStringSubmatch(err.Error()); m != nil {
+			pe.Key = m[1]
+			pe.Column = tomlValueColumn(sourceLine(data, pe.Line))
+		}
+	}
+
+	if pe.Line > 0 {
+		pe.Snippet = snippet(sourceLine(data, pe.Line), pe.Line, pe.Column)
+	}
+	return pe
+}
+
+var (
+	lineRe    = regexp.MustCompile(`\bline (\d+)\b`)
+	tomlKeyRe = regexp.MustCompile(`\(last key "([^"]*)"\)`)
+)
+
+// errorLine returns the line number from a "line N" mention in msg, or 0.
+func errorLine(msg string) int {
+	m := lineRe.FindStringSubmatch(msg)
+	if m == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(m[1])
+	return n
+}
+
+// lineCol returns the 1-based line and column of the byte offset off in data.
+// Columns count runes, like the YAML decoder.
+func lineCol(data []byte, off int64) (line, col int) {
+	i := int(min(max(off, 0), int64(len(data))))
+	line = 1 + bytes.Count(data[:i], []byte("\n"))
+	col = 1 + utf8.RuneCount(data[bytes.LastIndexByte(data[:i], '\n')+1:i])
+	return line, col
+}
+
+// sourceLine returns the 1-based line n of data without its line ending.
+func sourceLine(data []byte, n int) string {
+	lines := strings.Split(string(data), "\n")
+	if n < 1 || n > len(lines) {
+		return ""
+	}
+	return strings.TrimRight(lines[n-1], "\r")
+}
+
+// snippet renders a source line prefixed with its number and, if col is known,
+// a second line with a caret under the column.
+func snippet(src string, line, col int) string {
+	s := fmt.Sprintf("%4d | %s", line, src)
+	if col > 0 {
+		// Keep tabs so that the caret lines up with the source.
+		pad := []rune(src)
+		if col-1 < len(pad) {
+			pad = pad[:col-1]
+		}
+		for i, r := range pad {
+			if r != '\t' {
+				pad[i] = ' '
+			}
+		}
+		s += fmt.Sprintf("\n%4s | %s^", "", string(pad))
+	}
+	return s
+}
+
+// yamlValueAt returns the key path and column of the value that starts on line
+// in a YAML document, for errors that only report the line.
+func yamlValueAt(data []byte, line int) (key string, col int) {
+	var doc yaml.Node
+	if line == 0 || yaml.Unmarshal(data, &doc) != nil || len(doc.Content) == 0 {
+		return "", 0
+	}
+	var find func(n *yaml.Node, prefix string) bool
+	find = func(n *yaml.Node, prefix string) bool {
+		switch n.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				k, v := n.Content[i], n.Content[i+1]
+				if v.Kind == yaml.ScalarNode && v.Line == line {
+					key, col = joinKey(prefix, k.Value), v.Column
+					return true
+				}
+				if find(v, joinKey(prefix, k.Value)) {
+					return true
+				}
+			}
+		case yaml.SequenceNode:
+			for i, c := range n.Content {
+				p := prefix + "[" + strconv.Itoa(i) + "]"
+				if c.Kind == yaml.ScalarNode && c.Line == line {
+					key, col = p, c.Column
+					return true
+				}
+				if find(c, p) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	find(doc.Content[0], "")
+	return key, col
+}
+
+// jsonValueAt returns the offset of the value at field, a path of keys and
+// indices separated by dots as in json.UnmarshalTypeError.Field, together with
+// the path in the "servers[1].host" form.
+func jsonValueAt(data []byte, field string) (off int64, key string, ok bool) {
+	if field == "" {
+		return 0, "", false
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var walk func(dotted, k string) bool
+	// walk consumes one value; it reports true once field has been found, with
+	// off pointing at its first byte.
+	walk = func(dotted, k string) bool {
+		start := dec.InputOffset()
+		tok, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		if dotted == field {
+			off, key = skipSeparators(data, start), k
+			return true
+		}
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				t, err := dec.Token()
+				if err != nil {
+					return false
+				}
+				name, _ := t.(string)
+				if walk(joinKey(dotted, name), joinKey(k, name)) {
+					return true
+				}
+			}
+			_, _ = dec.Token()
+			return false
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				if walk(joinKey(dotted, strconv.Itoa(i)), k+"["+strconv.Itoa(i)+"]") {
+					return true
+				}
+			}
+			_, _ = dec.Token()
+			return false
+		}
+		return false
+	}
+	ok = walk("", "")
+	return off, key, ok
+}
+
+// skipSeparators returns the offset of the first byte at or after off that is
+// not JSON whitespace or a separator.
+func skipSeparators(data []byte, off int64) int64 {
+	for off < int64(len(data)) && strings.IndexByte(" \t\r\n,:", data[off]) >= 0 {
+		off++
+	}
+	return off
+}
+
+// tomlValueColumn returns the column of the value in a "key = value" TOML line.
+func tomlValueColumn(src string) int {
+	i := strings.IndexByte(src, '=')
+	if i < 0 {
+		return 0
+	}
+	i++
+	for i < len(src) && (src[i] == ' ' || src[i] == '\t') {
+		i++
+	}
+	return i + 1
+}
@@ -0,0 +1,210 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type parseCfg struct {
+	Name   string `json:"name" yaml:"name" toml:"name"`
+	Server struct {
+		Port int `json:"port" yaml:"port" toml:"port"`
+	} `json:"server" yaml:"server" toml:"server"`
+	Servers []struct {
+		Port int `json:"port" yaml:"port" toml:"port"`
+	} `json:"servers" yaml:"servers" toml:"servers"`
+}
+
+func TestLoadFromFile_ParseError(t *testing.T) {
+	td := t.TempDir()
+
+	tests := []struct {
+		name     string
+		file     string
+		data     string
+		wantLine int
+		wantCol  int
+		wantKey  string
+		snippet  string
+	}{
+		{
+			name:     "yaml type error",
+			file:     "type.yaml",
+			data:     "name: x\nserver:\n  port: abc\n",
+			wantLine: 3,
+			wantCol:  9,
+			wantKey:  "server.port",
+			snippet:  "   3 |   port: abc\n     |         ^",
+		},
+		{
+			name:     "yaml type error in a sequence",
+			file:     "seq.yaml",
+			data:     "servers:\n  - port: 1\n  - port: x\n",
+			wantLine: 3,
+			wantCol:  11,
+			wantKey:  "servers[1].port",
+		},
+		{
+			name:     "yaml syntax error",
+			file:     "syntax.yaml",
+			data:     "name: x\nserver: 1\n  port: 2\n",
+			wantLine: 3,
+			snippet:  "   3 |   port: 2",
+		},
+		{
+			name:     "json syntax error",
+			file:     "syntax.json",
+			data:     "{\n  \"name\": \"x\",\n  \"server\": {\"port\": 1,}\n}\n",
+			wantLine: 3,
+			wantCol:  24,
+		},
+		{
+			name:     "json type error",
+			file:     "type.json",
+			data:     "{\n  \"servers\": [\n    {\"port\": 1},\n    {\"port\": true}\n  ]\n}\n",
+			wantLine: 4,
+			wantCol:  14,
+			wantKey:  "servers[1].port",
+			snippet:  "   4 |     {\"port\": true}\n     |              ^",
+		},
+		{
+			name:     "toml type error",
+			file:     "type.toml",
+			data:     "name = \"x\"\n[server]\nport = \"x\"\n",
+			wantLine: 3,
+			wantCol:  8,
+			wantKey:  "server.port",
+		},
+		{
+			name:     "toml syntax error",
+			file:     "syntax.toml",
+			data:     "name = \"x\n",
+			wantLine: 1,
+			wantKey:  "name",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(td, tt.file)
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+
+			err := loadFromFile(path, &parseCfg{})
+			if !errors.Is(err, ErrParse) {
+				t.Fatalf("error = %v, want ErrParse", err)
+			}
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("error %T is not a *ParseError", err)
+			}
+			if pe.Path != path || pe.Line != tt.wantLine || pe.Key != tt.wantKey {
+				t.Fatalf("got path=%q line=%d key=%q, want %q %d %q", pe.Path, pe.Line, pe.Key, path, tt.wantLine, tt.wantKey)
+			}
+			if tt.wantCol != 0 && pe.Column != tt.wantCol {
+				t.Fatalf("column = %d, want %d", pe.Column, tt.wantCol)
+			}
+			if tt.snippet != "" && pe.Snippet != tt.snippet {
+				t.Fatalf("snippet =\n%s\nwant\n%s", pe.Snippet, tt.snippet)
+			}
+			if pe.Err == nil {
+				t.Fatalf("decoder error not kept")
+			}
+		})
+	}
+}
+
+func TestParseError_Error(t *testing.T) {
+	inner := errors.New("boom")
+	tests := []struct {
+		err  *ParseError
+		want string
+	}{
+		{&ParseError{Path: "c.yml", Err: inner}, "parse config file c.yml: boom"},
+		{&ParseError{Path: "c.yml", Line: 3, Err: inner}, "parse config file c.yml:3: boom"},
+		{&ParseError{Path: "c.yml", Line: 3, Column: 7, Key: "a.b", Err: inner}, "parse config file c.yml:3:7: a.b: boom"},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+		if !errors.Is(tt.err, inner) {
+			t.Errorf("ParseError does not unwrap to the decoder error")
+		}
+	}
+}
+
+func TestProvider_Get_ParseErrorLayered(t *testing.T) {
+	td := t.TempDir()
+	path := filepath.Join(td, "config.yml")
+	overlay := filepath.Join(td, "config.dev.yml")
+	layer := filepath.Join(td, "base.json")
+	t.Setenv("MYAPP_CONFIG_PATH", path)
+
+	tests := []struct {
+		name        string
+		main, over  string
+		wantPath    string
+		wantLine    int
+		wantKey     string
+		interpolate bool
+	}{
+		{
+			name:     "error in the config file",
+			main:     "name: x\nservers:\n  - port: 1\nserver:\n  port: abc\n",
+			over:     "name: dev\n",
+			wantPath: path,
+			wantLine: 5,
+			wantKey:  "server.port",
+		},
+		{
+			name:     "error in the overlay",
+			main:     "name: x\n",
+			over:     "name: dev\n\nserver:\n  port: abc\n",
+			wantPath: overlay,
+			wantLine: 4,
+			wantKey:  "server.port",
+		},
+		{
+			name:        "error in an interpolated value",
+			main:        "name: x\n\nserver:\n  port: ${CFG_PORT}\n",
+			over:        "name: dev\n",
+			wantPath:    path,
+			wantLine:    4,
+			wantKey:     "server.port",
+			interpolate: true,
+		},
+	}
+	writeFile(t, layer, `{"name": "base"}`)
+	t.Setenv("CFG_PORT", "abc")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeFile(t, path, tt.main)
+			writeFile(t, overlay, tt.over)
+			p := New[parseCfg](
+				WithEnvPrefix[parseCfg]("MYAPP"),
+				WithFiles[parseCfg](layer),
+				WithProfile[parseCfg]("dev"),
+				WithInterpolation[parseCfg](tt.interpolate),
+			)
+			_, _, _, err := p.Get()
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("error = %v, want a *ParseError", err)
+			}
+			if pe.Path != tt.wantPath || pe.Line != tt.wantLine || pe.Key != tt.wantKey || pe.Snippet == "" {
+				t.Fatalf("got path=%q line=%d key=%q snippet=%q, want %q %d %q", pe.Path, pe.Line, pe.Key, pe.Snippet, tt.wantPath, tt.wantLine, tt.wantKey)
+			}
+		})
+	}
+
+	t.Run("merged document lines are not reported", func(t *testing.T) {
+		err := error(&ParseError{Path: "a.yml, b.yml", Err: mergedError{errors.New("yaml: unmarshal errors:\n  line 2: cannot unmarshal")}})
+		if want := "parse config file a.yml, b.yml: yaml: unmarshal errors:\n  cannot unmarshal"; err.Error() != want {
+			t.Fatalf("Error() = %q, want %q", err.Error(), want)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// WithProfile selects a profile overlay, such as "production": after the resolved
+// config file, e.g. config.yml, the Provider loads config.production.yml from the
+// same directory and deep-merges it over the other files. A missing overlay is
+// skipped, and it is never created. With WithEnvPrefix, ${PREFIX}_PROFILE selects
+// the profile too and takes precedence; an invalid name there fails with ErrEnv.
+// Panics if name is empty, contains a path separator or is "." or "..".
+func WithProfile[T any](name string) Option[T] {
+	return func(m *Provider[T]) {
+		if name == "" {
+			panic("config: WithProfile: name cannot be empty")
+		}
+		if err := checkProfile(name); err != nil {
+			panic("config: WithProfile: " + err.Error())
+		}
+		m.profile = name
+	}
+}
+
+// checkProfile returns an error if name cannot select an overlay next to the
+// config file.
+func checkProfile(name string) error {
+	switch {
+	case filepath.Base(name) != name:
+		return errors.New("name cannot contain a path separator")
+	case name == "." || name == "..":
+		return fmt.Errorf("name cannot be %q", name)
+	}
+	return nil
+}
+
+// profileName returns the selected profile, from ${PREFIX}_PROFILE or WithProfile.
+func (m *Provider[T]) profileName() (string, error) {
+	if m.envPrefix != "" {
+		name := m.envPrefix + "_PROFILE"
+		if profile := m.getenv(name); profile != "" {
+			if err := checkProfile(profile); err != nil {
+				return "", fmt.Errorf("%w: %s=%q: profile %w", ErrEnv, name, profile, err)
+			}
+			return profile, nil
+		}
+	}
+	return m.profile, nil
+}
+
+// profilePath returns the path of the profile overlay of the config file at path:
+// dir/config.yml becomes dir/config.<profile>.yml. It is empty without a path or
+// a profile.
+func profilePath(path, profile string) string {
+	if path == "" || profile == "" {
+		return ""
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + profile + ext
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProfilePath(t *testing.T) {
+	tests := []struct {
+		path, profile, want string
+	}{
+		{path: filepath.Join("dir", "config.yml"), profile: "production", want: filepath.Join("dir", "config.production.yml")},
+		{path: "settings.json", profile: "dev", want: "settings.dev.json"},
+		{path: "config.yml", profile: "", want: ""},
+		{path: "", profile: "dev", want: ""},
+	}
+	for _, tc := range tests {
+		if got := profilePath(tc.path, tc.profile); got != tc.want {
+			t.Errorf("profilePath(%q, %q) = %q, want %q", tc.path, tc.profile, got, tc.want)
+		}
+	}
+}
+
+func TestProvider_Get_Profile(t *testing.T) {
+	td := t.TempDir()
+	dir := filepath.Join(td, "app")
+	path := filepath.Join(dir, "config.yml")
+	writeFile(t, path, "name: app\nport: 80\nlabels:\n  env: dev\n  team: core\n")
+	writeFile(t, filepath.Join(dir, "config.production.yml"), "port: 443\nlabels:\n  env: prod\n")
+	writeFile(t, filepath.Join(dir, "config.staging.yml"), "port: 8443\n")
+
+	tests := []struct {
+		name      string
+		profile   string
+		envValue  string
+		wantPort  int
+		wantFiles []string
+	}{
+		{name: "no profile", wantPort: 80, wantFiles: []string{path}},
+		{name: "WithProfile", profile: "production", wantPort: 443, wantFiles: []string{path, filepath.Join(dir, "config.production.yml")}},
+		{name: "env wins", profile: "production", envValue: "staging", wantPort: 8443, wantFiles: []string{path, filepath.Join(dir, "config.staging.yml")}},
+		{name: "missing overlay", profile: "test", wantPort: 80, wantFiles: []string{path}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("MYAPP_CONFIG_PATH", path)
+			t.Setenv("MYAPP_PROFILE", tc.envValue)
+			opts := []Option[filesCfg]{WithEnvPrefix[filesCfg]("MYAPP")}
+			if tc.profile != "" {
+				opts = append(opts, WithProfile[filesCfg](tc.profile))
+			}
+			p := New[filesCfg](opts...)
+			cfg, _, _, err := p.Get()
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if cfg.Name != "app" || cfg.Port != tc.wantPort {
+				t.Fatalf("cfg = %+v, want name app and port %d", cfg, tc.wantPort)
+			}
+			if tc.profile == "production" && tc.envValue == "" {
+				if want := map[string]string{"env": "prod", "team": "core"}; !reflect.DeepEqual(cfg.Labels, want) {
+					t.Fatalf("Labels = %v, want %v", cfg.Labels, want)
+				}
+			}
+			if !reflect.DeepEqual(p.Files(), tc.wantFiles) {
+				t.Fatalf("Files = %v, want %v", p.Files(), tc.wantFiles)
+			}
+		})
+	}
+
+	t.Run("created file does not contain the overlay", func(t *testing.T) {
+		t.Setenv("XDG_CONFIG_HOME", filepath.Join(td, "xdg"))
+		overlay := filepath.Join(td, "xdg", "myapp", "config.production.yml")
+		writeFile(t, overlay, "port: 443\n")
+
+		p := New[filesCfg](
+			WithDefaultFn(func() *filesCfg { return &filesCfg{Port: 80} }),
+			WithPersistence[filesCfg]("myapp"),
+			WithProfile[filesCfg]("production"),
+		)
+		cfg, path, created, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if !created || cfg.Port != 443 {
+			t.Fatalf("created=%v cfg=%+v", created, cfg)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read created file: %v", err)
+		}
+		if !strings.Contains(string(data), "port: 80") {
+			t.Fatalf("created file = %q, want the default port", data)
+		}
+		if want := []string{path, overlay}; !reflect.DeepEqual(p.Files(), want) {
+			t.Fatalf("Files = %v, want %v", p.Files(), want)
+		}
+	})
+}
+
+func TestProvider_Get_ProfileEnvInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	writeFile(t, path, "name: app\n")
+	t.Setenv("MYAPP_CONFIG_PATH", path)
+	for _, value := range []string{filepath.Join("..", "x"), "..", "."} {
+		t.Setenv("MYAPP_PROFILE", value)
+		p := New[filesCfg](WithEnvPrefix[filesCfg]("MYAPP"), WithProfile[filesCfg]("production"))
+		if _, _, _, err := p.Get(); !errors.Is(err, ErrEnv) || !strings.Contains(err.Error(), "MYAPP_PROFILE") {
+			t.Fatalf("%q: Get error = %v, want ErrEnv naming MYAPP_PROFILE", value, err)
+		}
+		if _, err := p.SearchPaths(); !errors.Is(err, ErrEnv) {
+			t.Fatalf("%q: SearchPaths error = %v, want ErrEnv", value, err)
+		}
+	}
+}
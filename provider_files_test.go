@@ -0,0 +1,199 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type filesCfg struct {
+	Name   string            `json:"name" yaml:"name"`
+	Port   int               `json:"port" yaml:"port"`
+	Labels map[string]string `json:"labels" yaml:"labels"`
+	Hosts  []string          `json:"hosts" yaml:"hosts"`
+	DB     struct {
+		Host string `json:"host" yaml:"host"`
+		User string `json:"user" yaml:"user"`
+	} `json:"db" yaml:"db"`
+}
+
+func TestProvider_Get_Files(t *testing.T) {
+	td := t.TempDir()
+	system := filepath.Join(td, "etc", "config.yml")
+	user := filepath.Join(td, "user", "config.json")
+	project := filepath.Join(td, "project", "myapp.yml")
+	missing := filepath.Join(td, "missing.yml")
+
+	writeFile(t, system, "name: system\nport: 80\nlabels:\n  env: prod\n  team: core\nhosts: [a, b]\ndb:\n  host: db.local\n  user: root\n")
+	writeFile(t, user, `{"port": 8080, "labels": {"team": "web"}, "hosts": ["c"]}`)
+	writeFile(t, project, "db:\n  user: app\n")
+
+	t.Run("deep merge with replaced slices", func(t *testing.T) {
+		t.Setenv("MYAPP_CONFIG_PATH", project)
+		p := New[filesCfg](
+			WithEnvPrefix[filesCfg]("MYAPP"),
+			WithFiles[filesCfg](system, missing, user),
+		)
+		cfg, path, _, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if path != project {
+			t.Fatalf("path = %q, want %q", path, project)
+		}
+		if cfg.Name != "system" || cfg.Port != 8080 {
+			t.Fatalf("scalars not merged: %+v", cfg)
+		}
+		if want := map[string]string{"env": "prod", "team": "web"}; !reflect.DeepEqual(cfg.Labels, want) {
+			t.Fatalf("Labels = %v, want %v", cfg.Labels, want)
+		}
+		if want := []string{"c"}; !reflect.DeepEqual(cfg.Hosts, want) {
+			t.Fatalf("Hosts = %v, want %v", cfg.Hosts, want)
+		}
+		if cfg.DB.Host != "db.local" || cfg.DB.User != "app" {
+			t.Fatalf("nested struct not merged: %+v", cfg.DB)
+		}
+		if want := []string{system, user, project}; !reflect.DeepEqual(p.Files(), want) {
+			t.Fatalf("Files = %v, want %v", p.Files(), want)
+		}
+	})
+
+	t.Run("appended slices", func(t *testing.T) {
+		t.Setenv("MYAPP_CONFIG_PATH", "")
+		p := New[filesCfg](
+			WithEnvPrefix[filesCfg]("MYAPP"),
+			WithFiles[filesCfg](system, user),
+			WithSliceMerge[filesCfg](SliceAppend),
+		)
+		cfg, _, _, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if want := []string{"a", "b", "c"}; !reflect.DeepEqual(cfg.Hosts, want) {
+			t.Fatalf("Hosts = %v, want %v", cfg.Hosts, want)
+		}
+	})
+
+	t.Run("persistent file is created over the layers", func(t *testing.T) {
+		t.Setenv("XDG_CONFIG_HOME", filepath.Join(td, "xdg"))
+		t.Setenv("XDG_CONFIG_DIRS", filepath.Join(td, "nosys"))
+		base := filepath.Join(td, "base", "config.yml")
+		writeFile(t, base, "name: base\ndb:\n  host: db.local\n")
+		p := New[filesCfg](
+			WithPersistence[filesCfg]("myapp"),
+			WithDefaultFn[filesCfg](func() *filesCfg { return &filesCfg{Name: "default", Port: 8080} }),
+			WithFiles[filesCfg](base),
+		)
+		cfg, path, created, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if !created || cfg.Name != "base" || cfg.Port != 8080 || cfg.DB.Host != "db.local" {
+			t.Fatalf("created=%v cfg=%+v", created, cfg)
+		}
+		if want := []string{base, path}; !reflect.DeepEqual(p.Files(), want) {
+			t.Fatalf("Files = %v, want %v", p.Files(), want)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read created file: %v", err)
+		}
+		for _, s := range []string{"name:", "base", "host:", "db.local"} {
+			if strings.Contains(string(data), s) {
+				t.Fatalf("created file contains %q from the layer: %q", s, data)
+			}
+		}
+		if !strings.Contains(string(data), "port: 8080") || !strings.Contains(string(data), "user:") {
+			t.Fatalf("created file lacks the defaults: %q", data)
+		}
+
+		// Later edits of the layer are not shadowed by the created file.
+		writeFile(t, base, "name: edited\ndb:\n  host: db.edited\n")
+		if err := p.Reload(context.Background()); err != nil {
+			t.Fatalf("Reload: %v", err)
+		}
+		cfg, _, _, _ = p.Get()
+		if cfg.Name != "edited" || cfg.DB.Host != "db.edited" || cfg.Port != 8080 {
+			t.Fatalf("cfg after editing the layer = %+v", cfg)
+		}
+	})
+
+	t.Run("persistent file listed as a layer", func(t *testing.T) {
+		xdg := filepath.Join(td, "xdg-listed")
+		t.Setenv("XDG_CONFIG_HOME", xdg)
+		t.Setenv("XDG_CONFIG_DIRS", filepath.Join(td, "nosys"))
+		persisted := filepath.Join(xdg, "app", "config.yml")
+		local := filepath.Join(td, "listed", "myapp.yml")
+		writeFile(t, persisted, "name: user\nport: 1\n")
+		writeFile(t, local, "port: 2\n")
+
+		for name, opt := range map[string]Option[filesCfg]{
+			"WithFiles":     WithFiles[filesCfg](persisted, local),
+			"WithConfigDir": WithConfigDir[filesCfg](filepath.Dir(persisted)),
+		} {
+			p := New[filesCfg](WithPersistence[filesCfg]("app"), opt)
+			for i := 0; i < 2; i++ {
+				cfg, path, created, err := p.Get()
+				if err != nil {
+					t.Fatalf("%s: Get: %v", name, err)
+				}
+				if created || path != persisted || cfg.Name != "user" {
+					t.Fatalf("%s: created=%v path=%q cfg=%+v", name, created, path, cfg)
+				}
+				if name == "WithFiles" && cfg.Port != 2 {
+					t.Fatalf("%s: Port = %d, want the later layer to win", name, cfg.Port)
+				}
+				if data, _ := os.ReadFile(persisted); string(data) != "name: user\nport: 1\n" {
+					t.Fatalf("%s: persisted file rewritten: %q", name, data)
+				}
+				if files := p.Files(); len(files) != len(filePaths(files, "")) {
+					t.Fatalf("%s: Files lists a file twice: %v", name, files)
+				}
+				if err := p.Reload(context.Background()); err != nil {
+					t.Fatalf("%s: Reload: %v", name, err)
+				}
+			}
+		}
+	})
+
+	t.Run("no files", func(t *testing.T) {
+		p := New[filesCfg](WithFiles[filesCfg](missing))
+		if _, _, _, err := p.Get(); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if files := p.Files(); len(files) != 0 {
+			t.Fatalf("Files = %v, want none", files)
+		}
+	})
+}
+
+func TestMergeTrees(t *testing.T) {
+	dst := map[string]interface{}{
+		"a": 1,
+		"m": map[string]interface{}{"x": 1, "y": 2},
+		"s": []interface{}{1},
+	}
+	src := map[string]interface{}{
+		"b": 2,
+		"m": map[string]interface{}{"y": 3},
+		"s": []interface{}{2},
+	}
+
+	got := mergeTrees(dst, src, SliceAppend)
+	want := map[string]interface{}{
+		"a": 1,
+		"b": 2,
+		"m": map[string]interface{}{"x": 1, "y": 3},
+		"s": []interface{}{1, 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("mergeTrees = %v, want %v", got, want)
+	}
+
+	if got := mergeTrees(map[string]interface{}{"k": "v"}, []interface{}{1}, SliceReplace); !reflect.DeepEqual(got, []interface{}{1}) {
+		t.Fatalf("mismatched kinds: got %v", got)
+	}
+}
@@ -737,3 +737,134 @@ func TestProvider_Get_Concurrent_LoadExisting(t *testing.T) {
 		t.Fatalf("expected exactly one 'loaded from' message, got: %q", got)
 	}
 }
+
+func TestProvider_Get_RetryOnError(t *testing.T) {
+	td := t.TempDir()
+
+	t.Run("default: init error is cached", func(t *testing.T) {
+		path := filepath.Join(td, "cached", "config.yaml")
+		writeFile(t, path, "name: [unclosed\n")
+		t.Setenv("MYAPP_CONFIG_PATH", path)
+
+		p := New[testCfg2](
+			WithEnvPrefix[testCfg2]("MYAPP"),
+			WithDefaultFn[testCfg2](defFn),
+		)
+		_, _, _, err1 := p.Get()
+		if !errors.Is(err1, ErrParse) {
+			t.Fatalf("first Get error = %v, want ErrParse", err1)
+		}
+
+		writeFile(t, path, "name: fixed\n")
+		if _, _, _, err2 := p.Get(); err2 != err1 {
+			t.Fatalf("second Get error = %v, want cached %v", err2, err1)
+		}
+	})
+
+	t.Run("WithRetryOnError: retries until success, then memoizes", func(t *testing.T) {
+		path := filepath.Join(td, "retry", "config.yaml")
+		writeFile(t, path, "name: [unclosed\n")
+		t.Setenv("MYAPP_CONFIG_PATH", path)
+
+		p := New[testCfg2](
+			WithEnvPrefix[testCfg2]("MYAPP"),
+			WithDefaultFn[testCfg2](defFn),
+			WithRetryOnError[testCfg2](),
+		)
+		if _, _, _, err := p.Get(); !errors.Is(err, ErrParse) {
+			t.Fatalf("first Get error = %v, want ErrParse", err)
+		}
+		if _, _, _, err := p.Get(); !errors.Is(err, ErrParse) {
+			t.Fatalf("second Get error = %v, want ErrParse (file still bad)", err)
+		}
+
+		writeFile(t, path, "name: fixed\n")
+		cfg1, _, _, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get after fix: %v", err)
+		}
+		if cfg1.Name != "fixed" {
+			t.Fatalf("Name = %q, want %q", cfg1.Name, "fixed")
+		}
+
+		// Once successful, the value is memoized even if the file changes or breaks.
+		writeFile(t, path, "name: [broken again\n")
+		cfg2, _, _, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get after success: %v", err)
+		}
+		if cfg2 != cfg1 {
+			t.Fatalf("expected memoized pointer after success")
+		}
+	})
+
+	t.Run("WithRetryOnError: concurrent callers share one success", func(t *testing.T) {
+		path := filepath.Join(td, "retry-conc", "config.yaml")
+		writeFile(t, path, "name: ok\n")
+		t.Setenv("MYAPP_CONFIG_PATH", path)
+
+		p := New[testCfg2](
+			WithEnvPrefix[testCfg2]("MYAPP"),
+			WithDefaultFn[testCfg2](defFn),
+			WithRetryOnError[testCfg2](),
+		)
+
+		const n = 16
+		ptrs := make(chan *testCfg2, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cfg, _, _, err := p.Get()
+				if err != nil {
+					t.Errorf("Get: %v", err)
+				}
+				ptrs <- cfg
+			}()
+		}
+		wg.Wait()
+		close(ptrs)
+
+		first := <-ptrs
+		for cfg := range ptrs {
+			if cfg != first {
+				t.Fatalf("cfg pointer mismatch: %p vs %p", cfg, first)
+			}
+		}
+	})
+}
+
+func TestProvider_Get_FileName(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", td)
+
+	newP := func(name string) *Provider[testCfg2] {
+		return New[testCfg2](
+			WithPersistence[testCfg2]("shared"),
+			WithFileName[testCfg2](name),
+			WithDefaultFn[testCfg2](defFn),
+		)
+	}
+
+	_, path, created, err := newP("tool-a.json").Get()
+	if err != nil || !created {
+		t.Fatalf("Get: created=%v err=%v", created, err)
+	}
+	if want := filepath.Join(td, "shared", "tool-a.json"); path != want {
+		t.Fatalf("path = %q, want %q", path, want)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !strings.Contains(string(data), `"name": "default"`) {
+		t.Fatalf("expected a JSON file, got %q", data)
+	}
+
+	// A second tool sharing the directory keeps its own file.
+	_, pathB, created, err := newP("tool-b.yml").Get()
+	if err != nil || !created || pathB == path {
+		t.Fatalf("Get: path=%q created=%v err=%v", pathB, created, err)
+	}
+}
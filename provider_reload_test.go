@@ -0,0 +1,193 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestProvider_Reload(t *testing.T) {
+	td := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(td, "xdg"))
+	t.Setenv("HOME", "")
+	t.Setenv("USERPROFILE", "")
+
+	newProvider := func(t *testing.T, path string) *Provider[testCfg2] {
+		t.Helper()
+		t.Setenv("MYAPP_CONFIG_PATH", path)
+		return New[testCfg2](
+			WithEnvPrefix[testCfg2]("MYAPP"),
+			WithDefaultFn[testCfg2](defFn),
+		)
+	}
+
+	t.Run("picks up file changes and keeps old pointer intact", func(t *testing.T) {
+		path := filepath.Join(td, "changes", "config.yaml")
+		writeFile(t, path, "name: first\ncount: 1\n")
+		p := newProvider(t, path)
+
+		old, _, _, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+
+		writeFile(t, path, "name: second\ncount: 2\n")
+		if err := p.Reload(context.Background()); err != nil {
+			t.Fatalf("Reload: %v", err)
+		}
+
+		cur, gotPath, _, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get after Reload: %v", err)
+		}
+		if cur == old {
+			t.Fatalf("expected a new pointer after Reload")
+		}
+		if cur.Name != "second" || cur.Count != 2 {
+			t.Fatalf("reloaded cfg mismatch: %+v", cur)
+		}
+		if old.Name != "first" || old.Count != 1 {
+			t.Fatalf("old cfg was mutated: %+v", old)
+		}
+		if gotPath != path {
+			t.Fatalf("path = %q, want %q", gotPath, path)
+		}
+	})
+
+	t.Run("failure keeps previous value", func(t *testing.T) {
+		path := filepath.Join(td, "failure", "config.yaml")
+		writeFile(t, path, "name: good\ncount: 3\n")
+		p := newProvider(t, path)
+
+		before, _, _, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+
+		writeFile(t, path, "name: [unclosed\n")
+		err = p.Reload(context.Background())
+		if !errors.Is(err, ErrParse) {
+			t.Fatalf("Reload error = %v, want ErrParse", err)
+		}
+
+		after, _, _, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get after failed Reload: %v", err)
+		}
+		if after != before || after.Name != "good" {
+			t.Fatalf("expected previous value to be kept, got %+v", after)
+		}
+	})
+
+	t.Run("before Get initializes the provider", func(t *testing.T) {
+		path := filepath.Join(td, "first", "config.yaml")
+		writeFile(t, path, "name: initial\n")
+		p := newProvider(t, path)
+
+		if err := p.Reload(context.Background()); err != nil {
+			t.Fatalf("Reload: %v", err)
+		}
+		cfg, _, _, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if cfg.Name != "initial" {
+			t.Fatalf("Name = %q, want %q", cfg.Name, "initial")
+		}
+	})
+
+	t.Run("successful reload clears cached init error", func(t *testing.T) {
+		path := filepath.Join(td, "recover", "config.yaml")
+		writeFile(t, path, "name: [unclosed\n")
+		p := newProvider(t, path)
+
+		if _, _, _, err := p.Get(); err == nil {
+			t.Fatalf("expected init error")
+		}
+
+		writeFile(t, path, "name: fixed\n")
+		if err := p.Reload(context.Background()); err != nil {
+			t.Fatalf("Reload: %v", err)
+		}
+		cfg, _, _, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get after Reload: %v", err)
+		}
+		if cfg.Name != "fixed" {
+			t.Fatalf("Name = %q, want %q", cfg.Name, "fixed")
+		}
+	})
+
+	t.Run("canceled context aborts reload", func(t *testing.T) {
+		path := filepath.Join(td, "canceled", "config.yaml")
+		writeFile(t, path, "name: kept\n")
+		p := newProvider(t, path)
+		if _, _, _, err := p.Get(); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := p.Reload(ctx); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Reload error = %v, want context.Canceled", err)
+		}
+		cfg, _, _, _ := p.Get()
+		if cfg.Name != "kept" {
+			t.Fatalf("Name = %q, want %q", cfg.Name, "kept")
+		}
+	})
+}
+
+func TestProvider_Reload_ConcurrentGet(t *testing.T) {
+	td := t.TempDir()
+	path := filepath.Join(td, "conc", "config.yaml")
+	writeFile(t, path, "name: v0\ncount: 0\n")
+	t.Setenv("MYAPP_CONFIG_PATH", path)
+
+	p := New[testCfg2](
+		WithEnvPrefix[testCfg2]("MYAPP"),
+		WithDefaultFn[testCfg2](defFn),
+	)
+	if _, _, _, err := p.Get(); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				cfg, _, _, err := p.Get()
+				if err != nil {
+					t.Errorf("Get: %v", err)
+					return
+				}
+				// Name and Count are always written together, so a reader must
+				// never observe a mix of two versions.
+				if want := "v" + strings.Repeat("x", cfg.Count); cfg.Count > 0 && cfg.Name != want {
+					t.Errorf("inconsistent cfg: %+v", cfg)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 1; i <= 5; i++ {
+		writeFile(t, path, "name: v"+strings.Repeat("x", i)+"\ncount: "+string(rune('0'+i))+"\n")
+		if err := p.Reload(context.Background()); err != nil {
+			t.Fatalf("Reload: %v", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
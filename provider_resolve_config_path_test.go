@@ -2,6 +2,7 @@ package config
 
 import (
 	"bytes"
+	"flag"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -26,6 +27,14 @@ func newProvider(opts ...Option[testCfg]) *Provider[testCfg] {
 	return New[testCfg](opts...) // New already injects defaultFn if nil
 }
 
+func mustAbs(p string) string {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		panic(err)
+	}
+	return abs
+}
+
 // ---- Tests ----
 
 func TestProvider_resolveConfigPath(t *testing.T) {
@@ -66,6 +75,45 @@ func TestProvider_resolveConfigPath(t *testing.T) {
 				configPath: "/tmp/override/config.yml",
 			},
 		},
+		{
+			name: "WithConfigPath takes precedence over persistence",
+			setup: func(t *testing.T) {
+				t.Setenv(prefix+"_CONFIG_PATH", "")
+			},
+			opts: []Option[testCfg]{
+				WithEnvPrefix[testCfg](prefix),
+				WithPersistence[testCfg](dirName),
+				WithConfigPath[testCfg]("/tmp/explicit/config.yml"),
+			},
+			want: want{
+				configPath: "/tmp/explicit/config.yml",
+			},
+		},
+		{
+			name: "env override takes precedence over WithConfigPath",
+			setup: func(t *testing.T) {
+				t.Setenv(prefix+"_CONFIG_PATH", "/tmp/override/config.yml")
+			},
+			opts: []Option[testCfg]{
+				WithEnvPrefix[testCfg](prefix),
+				WithConfigPath[testCfg]("/tmp/explicit/config.yml"),
+			},
+			want: want{
+				configPath: "/tmp/override/config.yml",
+			},
+		},
+		{
+			name: "relative path is resolved against the working directory",
+			setup: func(t *testing.T) {
+				t.Setenv(prefix+"_CONFIG_PATH", filepath.Join("conf", "app.yml"))
+			},
+			opts: []Option[testCfg]{
+				WithEnvPrefix[testCfg](prefix),
+			},
+			want: want{
+				configPath: mustAbs(filepath.Join("conf", "app.yml")),
+			},
+		},
 		{
 			name: "non-persistent, no dirName => no path, no error",
 			setup: func(t *testing.T) {
@@ -145,6 +193,22 @@ func TestProvider_resolveConfigPath(t *testing.T) {
 				// configPath asserted dynamically
 			},
 		},
+		{
+			name: "persistent: custom file name => join(XDG_CONFIG_HOME, dirName, name)",
+			setup: func(t *testing.T) {
+				t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg")
+				t.Setenv(prefix+"_CONFIG_PATH", "")
+				outBuf.Reset()
+				errBuf.Reset()
+			},
+			opts: []Option[testCfg]{
+				WithPersistence[testCfg](dirName),
+				WithFileName[testCfg]("settings.json"),
+			},
+			want: want{
+				configPath: filepath.Join("/tmp/xdg", dirName, "settings.json"),
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -164,7 +228,7 @@ func TestProvider_resolveConfigPath(t *testing.T) {
 			}
 
 			// Call resolveConfigPath
-			err := p.resolveConfigPath()
+			path, err := p.resolveConfigPath()
 
 			// Assertions on error
 			if tt.want.errContains != "" {
@@ -177,18 +241,18 @@ func TestProvider_resolveConfigPath(t *testing.T) {
 
 			// Assertions on computed path
 			if tt.want.configPath != "" {
-				if p.configPath != tt.want.configPath {
-					t.Fatalf("configPath = %q, want %q", p.configPath, tt.want.configPath)
+				if path != tt.want.configPath {
+					t.Fatalf("configPath = %q, want %q", path, tt.want.configPath)
 				}
 			} else {
 				// For cases where we expect a default (UserConfigDir + dirName)
 				if contains := strings.Contains(tt.name, "UserConfigDir"); contains && tt.want.errContains == "" && p.persist {
 					// Only when we expect a real path and no error in persistent mode
-					if p.configPath == "" && !strings.Contains(tt.name, "error") {
+					if path == "" && !strings.Contains(tt.name, "error") {
 						t.Fatalf("configPath is empty; expected a joined path")
 					}
-					if p.configPath != "" && !strings.HasSuffix(p.configPath, filepath.Join(dirName, configFileName)) {
-						t.Fatalf("configPath %q does not end with %q", p.configPath, filepath.Join(dirName, configFileName))
+					if path != "" && !strings.HasSuffix(path, filepath.Join(dirName, p.fileName)) {
+						t.Fatalf("configPath %q does not end with %q", path, filepath.Join(dirName, p.fileName))
 					}
 				}
 			}
@@ -208,3 +272,52 @@ func TestProvider_resolveConfigPath(t *testing.T) {
 		})
 	}
 }
+
+func TestProvider_WithConfigFlag(t *testing.T) {
+	td := t.TempDir()
+	flagPath := filepath.Join(td, "flag.yml")
+	envPath := filepath.Join(td, "env.yml")
+	writeFile(t, flagPath, "name: flag\n")
+	writeFile(t, envPath, "name: env\n")
+	t.Setenv("MYAPP_CONFIG_PATH", envPath)
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantPath string
+	}{
+		{name: "flag wins over env", args: []string{"--config", flagPath}, wantPath: flagPath},
+		{name: "env when flag unset", args: nil, wantPath: envPath},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			p := New[testCfg2](
+				WithEnvPrefix[testCfg2]("MYAPP"),
+				WithConfigPath[testCfg2](filepath.Join(td, "explicit.yml")),
+				WithConfigFlag[testCfg2](fs),
+			)
+			if err := fs.Parse(tc.args); err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			cfg, path, _, err := p.Get()
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if path != tc.wantPath || cfg.Name != strings.TrimSuffix(filepath.Base(tc.wantPath), ".yml") {
+				t.Fatalf("path=%q cfg=%+v, want %q", path, cfg, tc.wantPath)
+			}
+		})
+	}
+
+	t.Run("flag already defined panics", func(t *testing.T) {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.String(ConfigFlagName, "", "")
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic, got none")
+			}
+		}()
+		_ = New[testCfg2](WithConfigFlag[testCfg2](fs))
+	})
+}
@@ -0,0 +1,156 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// funcSource is a Source backed by a function, e.g. a CLI flag or secrets layer.
+type funcSource struct {
+	name string
+	fn   func(cfg *testCfg2) error
+}
+
+func (s funcSource) Name() string { return s.name }
+
+func (s funcSource) Load(_ context.Context, target any) error {
+	return s.fn(target.(*testCfg2))
+}
+
+func TestProvider_Get_Sources(t *testing.T) {
+	td := t.TempDir()
+	path := filepath.Join(td, "config.yaml")
+	writeFile(t, path, "name: fromfile\ncount: 2\n")
+	t.Setenv("MYAPP_CONFIG_PATH", path)
+	t.Setenv("MYAPP_NAME", "fromenv")
+
+	flags := funcSource{name: "flags", fn: func(cfg *testCfg2) error {
+		cfg.Name = "fromflags"
+		return nil
+	}}
+
+	tests := []struct {
+		name      string
+		sources   []Source
+		wantName  string
+		wantCount int
+	}{
+		{
+			name:      "default chain is file then env",
+			wantName:  "fromenv",
+			wantCount: 2,
+		},
+		{
+			name:      "custom source after built-ins wins",
+			sources:   []Source{ProviderFileSource(), ProviderEnvSource(), flags},
+			wantName:  "fromflags",
+			wantCount: 2,
+		},
+		{
+			name:      "reordered: env is overridden by file",
+			sources:   []Source{ProviderEnvSource(), ProviderFileSource()},
+			wantName:  "fromfile",
+			wantCount: 2,
+		},
+		{
+			name:      "file only",
+			sources:   []Source{ProviderFileSource()},
+			wantName:  "fromfile",
+			wantCount: 2,
+		},
+		{
+			name:      "standalone file and env sources",
+			sources:   []Source{FileSource(path), EnvSource("MYAPP")},
+			wantName:  "fromenv",
+			wantCount: 2,
+		},
+		{
+			name:      "empty chain keeps defaults",
+			sources:   []Source{},
+			wantName:  "default",
+			wantCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []Option[testCfg2]{WithEnvPrefix[testCfg2]("MYAPP"), WithDefaultFn[testCfg2](defFn)}
+			if tt.sources != nil {
+				opts = append(opts, WithSources[testCfg2](tt.sources...))
+			}
+			cfg, _, _, err := New[testCfg2](opts...).Get()
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if cfg.Name != tt.wantName || cfg.Count != tt.wantCount {
+				t.Fatalf("got %+v, want Name=%q Count=%d", cfg, tt.wantName, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestProvider_Get_SourcesBeforeCreatedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("MYAPP_CONFIG_PATH", path)
+	t.Setenv("MYAPP_COUNT", "7")
+	secrets := funcSource{name: "secrets", fn: func(cfg *testCfg2) error {
+		cfg.Name = "s3cret"
+		return nil
+	}}
+
+	p := New[testCfg2](
+		WithEnvPrefix[testCfg2]("MYAPP"),
+		WithDefaultFn[testCfg2](defFn),
+		WithPersistence[testCfg2]("myapp"),
+		WithSources[testCfg2](secrets, ProviderEnvSource(), ProviderFileSource()),
+	)
+	cfg, _, created, err := p.Get()
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !created || cfg.Name != "s3cret" || cfg.Count != 7 {
+		t.Fatalf("created=%v cfg=%+v", created, cfg)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read created file: %v", err)
+	}
+	if strings.Contains(string(data), "s3cret") || !strings.Contains(string(data), "name: default") || !strings.Contains(string(data), "count: 1") {
+		t.Fatalf("created file = %q, want the defaults only", data)
+	}
+}
+
+func TestProvider_Get_SourceError(t *testing.T) {
+	t.Setenv("MYAPP_CONFIG_PATH", "")
+	boom := errors.New("boom")
+	p := New[testCfg2](
+		WithEnvPrefix[testCfg2]("MYAPP"),
+		WithSources[testCfg2](funcSource{name: "secrets", fn: func(*testCfg2) error { return boom }}),
+	)
+	_, _, _, err := p.Get()
+	if !errors.Is(err, ErrSource) || !errors.Is(err, boom) {
+		t.Fatalf("error = %v, want ErrSource wrapping boom", err)
+	}
+}
+
+func TestFileSource_Missing(t *testing.T) {
+	cfg := testCfg2{Name: "kept"}
+	for _, path := range []string{"", filepath.Join(t.TempDir(), "missing.yaml")} {
+		if err := FileSource(path).Load(context.Background(), &cfg); err != nil {
+			t.Fatalf("FileSource(%q): %v", path, err)
+		}
+	}
+	if cfg.Name != "kept" {
+		t.Fatalf("cfg modified: %+v", cfg)
+	}
+}
+
+func TestBuiltinSource_LoadOutsideChain(t *testing.T) {
+	if err := ProviderEnvSource().Load(context.Background(), &testCfg2{}); err == nil {
+		t.Fatalf("expected error when loading a placeholder directly")
+	}
+}
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ygrebnov/config/streams"
+)
+
+type change struct {
+	old, new *testCfg2
+}
+
+// startWatch builds a fast-polling Provider for path, starts Watch in the
+// background and returns a channel of OnChange notifications.
+func startWatch(t *testing.T, path string, opts ...Option[testCfg2]) (*Provider[testCfg2], <-chan change) {
+	t.Helper()
+	t.Setenv("MYAPP_CONFIG_PATH", path)
+
+	changes := make(chan change, 16)
+	opts = append([]Option[testCfg2]{
+		WithEnvPrefix[testCfg2]("MYAPP"),
+		WithDefaultFn[testCfg2](defFn),
+		WithWatchInterval[testCfg2](5 * time.Millisecond),
+		WithWatchDebounce[testCfg2](20 * time.Millisecond),
+		WithOnChange[testCfg2](func(old, new *testCfg2) { changes <- change{old, new} }),
+	}, opts...)
+	p := New[testCfg2](opts...)
+	if _, _, _, err := p.Get(); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Watch(ctx) }()
+	// Give Watch time to take its baseline stat so that the caller's next
+	// write is observed as a change.
+	time.Sleep(50 * time.Millisecond)
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("Watch returned %v, want context.Canceled", err)
+		}
+	})
+	return p, changes
+}
+
+func waitChange(t *testing.T, changes <-chan change) change {
+	t.Helper()
+	select {
+	case c := <-changes:
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for OnChange")
+		return change{}
+	}
+}
+
+func TestProvider_Watch(t *testing.T) {
+	td := t.TempDir()
+
+	t.Run("modified in place", func(t *testing.T) {
+		path := filepath.Join(td, "modify", "config.yaml")
+		writeFile(t, path, "name: before\ncount: 1\n")
+		p, changes := startWatch(t, path)
+
+		writeFile(t, path, "name: after\ncount: 22\n")
+		c := waitChange(t, changes)
+		if c.old.Name != "before" || c.new.Name != "after" || c.new.Count != 22 {
+			t.Fatalf("unexpected change: old=%+v new=%+v", c.old, c.new)
+		}
+		if cfg, _, _, _ := p.Get(); cfg != c.new {
+			t.Fatalf("Get does not return the reloaded value")
+		}
+	})
+
+	t.Run("renamed over", func(t *testing.T) {
+		path := filepath.Join(td, "rename", "config.yaml")
+		writeFile(t, path, "name: before\n")
+		_, changes := startWatch(t, path)
+
+		if err := writeToFile(path, &testCfg2{Name: "renamed", Count: 5}); err != nil {
+			t.Fatalf("writeToFile: %v", err)
+		}
+		c := waitChange(t, changes)
+		if c.new.Name != "renamed" || c.new.Count != 5 {
+			t.Fatalf("unexpected new value: %+v", c.new)
+		}
+	})
+
+	t.Run("deleted falls back to defaults", func(t *testing.T) {
+		path := filepath.Join(td, "delete", "config.yaml")
+		writeFile(t, path, "name: fromfile\n")
+		_, changes := startWatch(t, path)
+
+		if err := os.Remove(path); err != nil {
+			t.Fatalf("remove: %v", err)
+		}
+		c := waitChange(t, changes)
+		if c.new.Name != "default" {
+			t.Fatalf("Name = %q, want %q", c.new.Name, "default")
+		}
+	})
+
+	t.Run("layer file modified", func(t *testing.T) {
+		layer := filepath.Join(td, "layer", "base.yaml")
+		path := filepath.Join(td, "layer", "config.yaml")
+		writeFile(t, layer, "count: 1\n")
+		writeFile(t, path, "name: top\n")
+		_, changes := startWatch(t, path, WithFiles[testCfg2](layer))
+
+		writeFile(t, layer, "count: 42\n")
+		c := waitChange(t, changes)
+		if c.new.Name != "top" || c.new.Count != 42 {
+			t.Fatalf("unexpected new value: %+v", c.new)
+		}
+	})
+
+	t.Run("file added to config dir", func(t *testing.T) {
+		dir := filepath.Join(td, "confd", "conf.d")
+		path := filepath.Join(td, "confd", "config.yaml")
+		writeFile(t, filepath.Join(dir, "10-base.yaml"), "count: 1\n")
+		writeFile(t, path, "name: top\n")
+		_, changes := startWatch(t, path, WithConfigDir[testCfg2](dir))
+
+		writeFile(t, filepath.Join(dir, "20-extra.yaml"), "count: 7\n")
+		c := waitChange(t, changes)
+		if c.new.Name != "top" || c.new.Count != 7 {
+			t.Fatalf("unexpected new value: %+v", c.new)
+		}
+	})
+
+	t.Run("included file modified", func(t *testing.T) {
+		inc := filepath.Join(td, "inc", "base.yaml")
+		path := filepath.Join(td, "inc", "config.yaml")
+		writeFile(t, inc, "count: 1\n")
+		writeFile(t, path, "include: base.yaml\nname: top\n")
+		_, changes := startWatch(t, path, WithIncludes[testCfg2]())
+
+		writeFile(t, inc, "count: 5\n")
+		c := waitChange(t, changes)
+		if c.new.Name != "top" || c.new.Count != 5 {
+			t.Fatalf("unexpected new value: %+v", c.new)
+		}
+	})
+
+	t.Run("file in MemFS modified", func(t *testing.T) {
+		mem := &MemFS{}
+		if err := mem.MkdirAll("mem", 0o700); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+		if err := mem.WriteFile("mem/config.yaml", []byte("name: before\n"), 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		_, changes := startWatch(t, "/mem/config.yaml", WithFS[testCfg2](mem))
+
+		if err := mem.WriteFile("mem/config.yaml", []byte("name: after\n"), 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if c := waitChange(t, changes); c.new.Name != "after" {
+			t.Fatalf("Name = %q, want %q", c.new.Name, "after")
+		}
+	})
+
+	t.Run("burst of writes is debounced into one reload", func(t *testing.T) {
+		path := filepath.Join(td, "burst", "config.yaml")
+		writeFile(t, path, "name: v0\n")
+		_, changes := startWatch(t, path, WithWatchDebounce[testCfg2](150*time.Millisecond))
+
+		for _, v := range []string{"v1", "v22", "v333", "v4444"} {
+			writeFile(t, path, "name: "+v+"\n")
+			time.Sleep(10 * time.Millisecond)
+		}
+		c := waitChange(t, changes)
+		if c.new.Name != "v4444" {
+			t.Fatalf("Name = %q, want last written value", c.new.Name)
+		}
+		select {
+		case extra := <-changes:
+			t.Fatalf("unexpected extra reload: %+v", extra.new)
+		case <-time.After(300 * time.Millisecond):
+		}
+	})
+
+	t.Run("reload error keeps watching and reports to ErrOut", func(t *testing.T) {
+		path := filepath.Join(td, "broken", "config.yaml")
+		writeFile(t, path, "name: good\n")
+		ts := streams.ThreadSafeBuffers()
+		p, changes := startWatch(t, path, WithStreams[testCfg2](ts))
+
+		writeFile(t, path, "name: [unclosed\n")
+		deadline := time.Now().Add(5 * time.Second)
+		for _, errOut := ts.Strings(); !strings.Contains(errOut, "reload of"); _, errOut = ts.Strings() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for reload warning")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		if cfg, _, _, _ := p.Get(); cfg.Name != "good" {
+			t.Fatalf("previous value not kept: %+v", cfg)
+		}
+
+		writeFile(t, path, "name: fixed\n")
+		if c := waitChange(t, changes); c.new.Name != "fixed" {
+			t.Fatalf("Name = %q, want %q", c.new.Name, "fixed")
+		}
+	})
+}
+
+func TestProvider_Watch_NoPath(t *testing.T) {
+	p := New[testCfg2](WithDefaultFn[testCfg2](defFn))
+	if err := p.Watch(context.Background()); !errors.Is(err, ErrNoConfigPath) {
+		t.Fatalf("Watch error = %v, want ErrNoConfigPath", err)
+	}
+}
+
+func TestFileState_Changed(t *testing.T) {
+	td := t.TempDir()
+	path := filepath.Join(td, "f.yaml")
+	var fio fileIO
+
+	missing := fio.statFile(path)
+	if missing.changed(fio.statFile(path)) {
+		t.Fatalf("missing file should not be reported as changed")
+	}
+
+	writeFile(t, path, "a: 1\n")
+	created := fio.statFile(path)
+	if !created.changed(missing) || !missing.changed(created) {
+		t.Fatalf("appearance/disappearance must be a change")
+	}
+	if created.changed(fio.statFile(path)) {
+		t.Fatalf("unchanged file reported as changed")
+	}
+
+	writeFile(t, path, "a: 12\n")
+	if !fio.statFile(path).changed(created) {
+		t.Fatalf("size change not detected")
+	}
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/ygrebnov/config/streams"
+)
+
+// Source is one layer of configuration applied by a Provider. Load applies the
+// layer's values onto target, a non-nil pointer to the config struct that already
+// holds the values of all previous layers. Name identifies the source in errors.
+type Source interface {
+	Name() string
+	Load(ctx context.Context, target any) error
+}
+
+// WithSources replaces the default chain of sources (the config file, then
+// environment overrides) with the given sources, applied in order so that later
+// sources override earlier ones. Use ProviderFileSource and ProviderEnvSource to
+// position the Provider's built-in file and environment layers in the chain.
+// Defaults, model defaults and validation still run before and after the chain.
+// Panics if a source is nil.
+func WithSources[T any](sources ...Source) Option[T] {
+	return func(m *Provider[T]) {
+		for _, src := range sources {
+			if src == nil {
+				panic("config: WithSources: source cannot be nil")
+			}
+		}
+		m.sources = append([]Source{}, sources...)
+	}
+}
+
+// builtinSource is a placeholder for one of the Provider's own layers. It is
+// replaced by the configured built-in source when the chain runs.
+type builtinSource string
+
+const (
+	providerFile builtinSource = "file"
+	providerEnv  builtinSource = "env"
+)
+
+func (s builtinSource) Name() string { return string(s) }
+
+func (s builtinSource) Load(context.Context, any) error {
+	return fmt.Errorf("config: %s placeholder source can only be used with WithSources", s)
+}
+
+// ProviderFileSource returns a placeholder for the Provider's built-in file layer
+// (path resolution, persistence and file creation) for use with WithSources.
+func ProviderFileSource() Source { return providerFile }
+
+// ProviderEnvSource returns a placeholder for the Provider's built-in environment
+// layer (WithEnvPrefix, WithLenientEnv, WithDecoder) for use with WithSources.
+func ProviderEnvSource() Source { return providerEnv }
+
+// FileSource returns a Source that loads the file at path with the codec
+// registered for its extension (YAML, JSON and TOML are built in). A missing
+// file or an empty path is skipped.
+func FileSource(path string) Source {
+	return &fileSource{path: path}
+}
+
+// EnvSource returns a Source that applies environment overrides named after
+// prefix, like the Provider's built-in layer configured with WithEnvPrefix.
+func EnvSource(prefix string) Source {
+	return &envSource{prefix: prefix}
+}
+
+// fileSource loads config files: the layers, then the files in dirs, then path
+// and the overlays (the profile overlay and project files), merged in order.
+// With create set, a missing path is created from defaults, never from the
+// target, which holds the values of earlier sources, and without the keys set by
+// the files merged before it.
+type fileSource struct {
+	fio      fileIO              // nil codecs means the registered codecs
+	defaults func() (any, error) // required with create
+	layers   []string
+	dirs     []string
+	path     string
+	overlays []string
+	policy   SliceMergePolicy
+	create   bool
+	streams  streams.IOStreams
+	created  bool
+	loaded   []string
+}
+
+func (s *fileSource) Name() string {
+	names := append(s.layers[:len(s.layers):len(s.layers)], s.dirs...)
+	return "file " + strings.Join(filePaths(append(append(names, s.path), s.overlays...), ""), ", ")
+}
+
+func (s *fileSource) Load(_ context.Context, target any) error {
+	fio := s.fio
+	if fio.codecs == nil {
+		fio.codecs = registered()
+	}
+	paths, err := fio.layerPaths(s.layers, s.dirs, s.path)
+	if err != nil {
+		return err
+	}
+	existing, err := fio.existingFiles(paths)
+	if err != nil {
+		return err
+	}
+	// Overlays already merged as a layer are skipped.
+	overlays := filePaths(append(paths[:len(paths):len(paths)], s.overlays...), "")[len(paths):]
+	overlay, err := fio.existingFiles(overlays)
+	if err != nil {
+		return err
+	}
+	// The resolved file may also be listed as a layer, which then keeps its
+	// place in the merge order; it must never be created over.
+	found := s.path != "" && slices.Contains(existing, s.path)
+	create := !found && s.path != "" && s.create
+	if !create {
+		// Merge the overlays with the other files; a created file must not
+		// contain them, so they are then applied separately.
+		existing, overlay = append(existing, overlay...), nil
+	}
+	if s.loaded, err = fio.loadFiles(existing, target, s.policy); err != nil {
+		return err
+	}
+
+	switch {
+	case create:
+		if pe := fio.ensurePath(s.path); pe != nil {
+			return errors.Join(ErrEnsureConfigDir, pe)
+		}
+
+		defaults, err := s.defaults()
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 || fio.interpolate {
+			tree, err := fio.fileTree(s.path, defaults)
+			if err != nil {
+				return err
+			}
+			// The created file is merged over the layers on the next load, so it
+			// must not contain the keys they set, or later edits of the layers
+			// would be shadowed.
+			if len(existing) > 0 {
+				layer, err := fio.layerTree(existing, s.policy)
+				if err != nil {
+					return err
+				}
+				tree = pruneTree(tree, layer)
+			}
+			// Default values are literal: they must read back unchanged.
+			if fio.interpolate {
+				tree = escapeTree(tree)
+			}
+			defaults = tree
+		}
+		if we := fio.writeFile(s.path, defaults); we != nil {
+			return errors.Join(ErrWrite, we)
+		}
+		s.created = true
+		s.loaded = append(s.loaded, s.path)
+		if s.streams != nil && s.streams.Out() != nil {
+			fmt.Fprintf(s.streams.Out(), "config: created new config at %s\n", s.path)
+		}
+	case found && s.create:
+		if s.streams != nil && s.streams.Out() != nil {
+			fmt.Fprintf(s.streams.Out(), "config: loaded from %s\n", s.path)
+		}
+	}
+	if len(overlay) > 0 {
+		loaded, err := fio.loadFiles(overlay, target, s.policy)
+		if err != nil {
+			return err
+		}
+		s.loaded = append(s.loaded, loaded...)
+	}
+	return nil
+}
+
+// envSource applies environment overrides, from the process environment over
+// the dotEnv files. Values that cannot be parsed are returned as *EnvError values
+// joined together, unless lenient is set.
+type envSource struct {
+	prefix   string
+	lenient  bool
+	decoders map[reflect.Type]func(string) (any, error)
+	dotEnv   []string
+	fio      fileIO         // reads the dotEnv files
+	env      func() envVars // nil means the process environment
+}
+
+func (s *envSource) Name() string { return "env" }
+
+func (s *envSource) Load(_ context.Context, target any) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return nil
+	}
+	env := processEnv()
+	if s.env != nil {
+		env = s.env()
+	}
+	if len(s.dotEnv) > 0 {
+		var err error
+		if env, err = s.fio.dotEnvVars(s.dotEnv, env); err != nil {
+			return err
+		}
+	}
+	l := &envLoader{env: env, prefix: s.prefix, decoders: s.decoders}
+	l.applyEnv(rv.Elem(), nil, nil)
+	if s.lenient {
+		return nil
+	}
+	return errors.Join(l.errs...)
+}
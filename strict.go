@@ -0,0 +1,378 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// StrictCodec is a Codec that can report the keys in data that do not map to a
+// field of v. It is used by WithStrict; files in formats whose codec does not
+// implement it are not checked. The built-in codecs implement StrictCodec.
+type StrictCodec interface {
+	Codec
+	UnknownFields(data []byte, v any) ([]*UnknownFieldError, error)
+}
+
+// UnknownFieldError reports a key in a config file that does not map to any field
+// of the config struct. It matches ErrUnknownField with errors.Is.
+type UnknownFieldError struct {
+	Path   string // config file path
+	Key    string // key path, e.g. "server.port" or "servers[1].host"
+	Line   int    // 1-based line of the key, 0 if unknown
+	Column int    // 1-based column of the key, 0 if unknown
+}
+
+func (e *UnknownFieldError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%v: %s: %s", ErrUnknownField, e.Path, e.Key)
+	}
+	return fmt.Sprintf("%v: %s:%d:%d: %s", ErrUnknownField, e.Path, e.Line, e.Column, e.Key)
+}
+
+func (e *UnknownFieldError) Unwrap() error { return ErrUnknownField }
+
+// WithStrict makes the Provider reject config files containing keys that do not
+// map to a field of T. Every unknown key is reported as an *UnknownFieldError
+// (matching ErrUnknownField); they are joined into a single error. The built-in
+// codecs use the strict modes of their decoders (yaml.Decoder.KnownFields,
+// json.Decoder.DisallowUnknownFields and toml.MetaData.Undecoded).
+func WithStrict[T any]() Option[T] {
+	return func(m *Provider[T]) {
+		m.strict = true
+	}
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// yamlUnknownField matches the yaml.TypeError message for a key without a field
+// when yaml.Decoder.KnownFields is set.
+var yamlUnknownField = regexp.MustCompile(`^line (\d+): field (.+) not found in type `)
+
+// UnknownFields decodes data with yaml.Decoder.KnownFields set and locates the
+// unknown keys it reports in the document.
+func (yamlCodec) UnknownFields(data []byte, v any) ([]*UnknownFieldError, error) {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Pointer {
+		return nil, nil
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err := dec.Decode(reflect.New(t.Elem()).Interface())
+	var te *yaml.TypeError
+	switch {
+	case err == nil || errors.Is(err, io.EOF):
+		return nil, nil
+	case !errors.As(err, &te):
+		return nil, err
+	}
+	keys := yamlKeys(data)
+	var unknown []*UnknownFieldError
+	for _, msg := range te.Errors {
+		m := yamlUnknownField.FindStringSubmatch(msg)
+		if m == nil {
+			continue
+		}
+		line, _ := strconv.Atoi(m[1])
+		u := &UnknownFieldError{Key: m[2], Line: line}
+		for i, k := range keys {
+			if k.line == line && k.name == m[2] {
+				u.Key, u.Column = k.path, k.col
+				keys = append(keys[:i], keys[i+1:]...)
+				break
+			}
+		}
+		unknown = append(unknown, u)
+	}
+	return unknown, nil
+}
+
+// yamlKey is a mapping key in a YAML document.
+type yamlKey struct {
+	name, path string
+	line, col  int
+}
+
+// yamlKeys returns the mapping keys of a YAML document with their key paths, in
+// document order. The content of an alias is listed where it is anchored.
+func yamlKeys(data []byte) []yamlKey {
+	var doc yaml.Node
+	if yaml.Unmarshal(data, &doc) != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	var keys []yamlKey
+	var walk func(n *yaml.Node, prefix string)
+	walk = func(n *yaml.Node, prefix string) {
+		switch n.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				k := n.Content[i]
+				keys = append(keys, yamlKey{name: k.Value, path: joinKey(prefix, k.Value), line: k.Line, col: k.Column})
+				walk(n.Content[i+1], joinKey(prefix, k.Value))
+			}
+		case yaml.SequenceNode:
+			for i, c := range n.Content {
+				walk(c, prefix+"["+strconv.Itoa(i)+"]")
+			}
+		}
+	}
+	walk(doc.Content[0], "")
+	return keys
+}
+
+// UnknownFields walks the keys of data in document order and asks a json.Decoder
+// with DisallowUnknownFields set whether each of them is known, by decoding a
+// document holding only the path to the key. Keys below an unknown key are not
+// reported.
+func (jsonCodec) UnknownFields(data []byte, v any) ([]*UnknownFieldError, error) {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Pointer {
+		return nil, nil
+	}
+	w := &jsonWalker{data: data, dec: json.NewDecoder(bytes.NewReader(data)), typ: t.Elem()}
+	if err := w.walk(nil, "", true); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return w.unknown, nil
+}
+
+// jsonWalker reads a JSON document token by token, so that unknown keys can be
+// reported with their position.
+type jsonWalker struct {
+	data    []byte
+	dec     *json.Decoder
+	typ     reflect.Type
+	unknown []*UnknownFieldError
+}
+
+// walk consumes one JSON value at path, a list of object keys and array indices.
+// With check set, the keys of its objects are checked.
+func (w *jsonWalker) walk(path []any, prefix string, check bool) error {
+	tok, err := w.dec.Token()
+	if err != nil {
+		return err
+	}
+	switch tok {
+	case json.Delim('{'):
+		for w.dec.More() {
+			off := w.dec.InputOffset()
+			tok, err := w.dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := tok.(string)
+			sub := append(path[:len(path):len(path)], key)
+			known := !check || w.known(sub)
+			if !known {
+				line, col := position(w.data, off)
+				w.unknown = append(w.unknown, &UnknownFieldError{Key: joinKey(prefix, key), Line: line, Column: col})
+			}
+			if err := w.walk(sub, joinKey(prefix, key), check && known); err != nil {
+				return err
+			}
+		}
+		_, err = w.dec.Token()
+		return err
+	case json.Delim('['):
+		for i := 0; w.dec.More(); i++ {
+			if err := w.walk(append(path[:len(path):len(path)], i), prefix+"["+strconv.Itoa(i)+"]", check); err != nil {
+				return err
+			}
+		}
+		_, err = w.dec.Token()
+		return err
+	}
+	return nil
+}
+
+// known reports whether encoding/json accepts the last key of path, whose
+// ancestors are known. Errors other than an unknown field, e.g. from a type that
+// decodes itself, count as known.
+func (w *jsonWalker) known(path []any) bool {
+	var doc any
+	for i := len(path) - 1; i >= 0; i-- {
+		switch p := path[i].(type) {
+		case string:
+			doc = map[string]any{p: doc}
+		case int:
+			elems := make([]any, p+1)
+			elems[p] = doc
+			doc = elems
+		}
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return true
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(reflect.New(w.typ).Interface())
+	return err == nil || !strings.HasPrefix(err.Error(), "json: unknown field ")
+}
+
+// position returns the 1-based line and column of the first token at or after
+// offset off in data, skipping whitespace and separators.
+func position(data []byte, off int64) (line, col int) {
+	return lineCol(data, skipSeparators(data, off))
+}
+
+// UnknownFields decodes data and reports the keys listed by
+// toml.MetaData.Undecoded, located in the document.
+func (tomlCodec) UnknownFields(data []byte, v any) ([]*UnknownFieldError, error) {
+	// Decode into a throwaway value: only the metadata is needed.
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Pointer {
+		return nil, nil
+	}
+	md, err := toml.Decode(string(data), reflect.New(t.Elem()).Interface())
+	if err != nil {
+		return nil, err
+	}
+	var unknown []*UnknownFieldError
+	reported := map[string]bool{}
+	for _, key := range md.Undecoded() {
+		// Report an unknown table once, not each of its keys.
+		if len(key) > 1 && reported[key[:len(key)-1].String()] {
+			reported[key.String()] = true
+			continue
+		}
+		reported[key.String()] = true
+		line, col := tomlKeyAt(data, key)
+		unknown = append(unknown, &UnknownFieldError{Key: key.String(), Line: line, Column: col})
+	}
+	return unknown, nil
+}
+
+// tomlKeyAt returns the 1-based line and column of the last segment of key in a
+// TOML document, where it is defined by a table header or a (dotted) key/value
+// line. It returns 0, 0 if key is not found, e.g. inside an inline table.
+func tomlKeyAt(data []byte, key toml.Key) (line, col int) {
+	var (
+		table []string
+		multi string // delimiter of an open multi-line string
+	)
+	for i, src := range strings.Split(string(data), "\n") {
+		if multi != "" {
+			if strings.Contains(src, multi) {
+				multi = ""
+			}
+			continue
+		}
+		s := strings.TrimLeft(src, " \t")
+		indent := len(src) - len(s)
+		if strings.HasPrefix(s, "[") {
+			open := 1
+			if strings.HasPrefix(s, "[[") {
+				open = 2
+			}
+			segs, cols, _ := tomlKeySegments(s[open:])
+			table = segs
+			if len(segs) > 0 && equalKeys(segs, key) {
+				return i + 1, indent + open + cols[len(cols)-1] + 1
+			}
+			continue
+		}
+		segs, cols, end := tomlKeySegments(s)
+		if len(segs) == 0 || end == len(s) || s[end] != '=' {
+			continue
+		}
+		full := append(table[:len(table):len(table)], segs...)
+		if n := len(key) - len(table); n > 0 && len(full) >= len(key) && equalKeys(full[:len(key)], key) {
+			return i + 1, indent + cols[n-1] + 1
+		}
+		for _, delim := range []string{`"""`, "'''"} {
+			if strings.Count(s[end:], delim) == 1 {
+				multi = delim
+			}
+		}
+	}
+	return 0, 0
+}
+
+// tomlKeySegments parses a possibly dotted TOML key at the start of s. It returns
+// the segments, their offsets in s and the offset of the byte after the key.
+func tomlKeySegments(s string) (segs []string, offs []int, end int) {
+	i := 0
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+			i++
+		}
+		start := i
+		switch {
+		case i < len(s) && (s[i] == '"' || s[i] == '\''):
+			j := strings.IndexByte(s[i+1:], s[i])
+			for s[i] == '"' && j > 0 && s[i+j] == '\\' {
+				k := strings.IndexByte(s[i+j+2:], '"')
+				if k < 0 {
+					j = -1
+					break
+				}
+				j += k + 1
+			}
+			if j < 0 {
+				return nil, nil, 0
+			}
+			seg := s[i+1 : i+1+j]
+			if s[i] == '"' {
+				if u, err := strconv.Unquote(s[i : i+j+2]); err == nil {
+					seg = u
+				}
+			}
+			segs = append(segs, seg)
+			i += j + 2
+		default:
+			for i < len(s) && isTOMLBareKeyByte(s[i]) {
+				i++
+			}
+			if i == start {
+				return nil, nil, 0
+			}
+			segs = append(segs, s[start:i])
+		}
+		offs = append(offs, start)
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+			i++
+		}
+		if i == len(s) || s[i] != '.' {
+			return segs, offs, i
+		}
+		i++
+	}
+}
+
+func isTOMLBareKeyByte(c byte) bool {
+	return c == '_' || c == '-' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+func equalKeys(segs []string, key toml.Key) bool {
+	if len(segs) != len(key) {
+		return false
+	}
+	for i := range segs {
+		if segs[i] != key[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Compile-time checks that the built-in codecs support strict mode.
+var (
+	_ StrictCodec = yamlCodec{}
+	_ StrictCodec = jsonCodec{}
+	_ StrictCodec = tomlCodec{}
+)
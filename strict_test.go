@@ -0,0 +1,233 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type strictServer struct {
+	Host string `yaml:"host" json:"host" toml:"host"`
+	Port int    `yaml:"port" json:"port" toml:"port"`
+}
+
+type strictBase struct {
+	Debug bool `yaml:"debug" json:"debug"`
+}
+
+type strictCfg struct {
+	strictBase `yaml:",inline"`
+
+	Name    string                  `yaml:"name" json:"name" toml:"name"`
+	Timeout time.Duration           `yaml:"timeout" json:"timeout" toml:"timeout"`
+	Server  strictServer            `yaml:"server" json:"server" toml:"server"`
+	Servers []strictServer          `yaml:"servers" json:"servers" toml:"servers"`
+	ByName  map[string]strictServer `yaml:"by_name" json:"by_name" toml:"by_name"`
+	Color   color                   `yaml:"color" json:"color" toml:"color"`
+	Extra   any                     `yaml:"extra" json:"extra" toml:"extra"`
+}
+
+func TestUnknownFields(t *testing.T) {
+	type pos struct {
+		Key          string
+		Line, Column int
+	}
+	tests := []struct {
+		name  string
+		codec StrictCodec
+		data  string
+		want  []pos
+	}{
+		{
+			name:  "yaml",
+			codec: yamlCodec{},
+			data: "name: x\n" +
+				"debug: true\n" +
+				"sever:\n" +
+				"  port: 1\n" +
+				"server:\n" +
+				"  prot: 2\n" +
+				"servers:\n" +
+				"  - host: a\n" +
+				"  - hots: b\n" +
+				"by_name:\n" +
+				"  main:\n" +
+				"    hostname: c\n" +
+				"color: red\n" +
+				"extra: {anything: 1}\n",
+			want: []pos{
+				{"sever", 3, 1},
+				{"server.prot", 6, 3},
+				{"servers[1].hots", 9, 5},
+				{"by_name.main.hostname", 12, 5},
+			},
+		},
+		{
+			name:  "yaml without unknown keys",
+			codec: yamlCodec{},
+			data:  "name: x\ntimeout: 1s\nserver:\n  host: h\n",
+		},
+		{
+			name:  "json",
+			codec: jsonCodec{},
+			data: "{\n" +
+				"  \"NAME\": \"x\",\n" +
+				"  \"debug\": true,\n" +
+				"  \"sever\": {\"port\": 1},\n" +
+				"  \"servers\": [{\"host\": \"a\"}, {\"hots\": \"b\"}],\n" +
+				"  \"extra\": {\"anything\": [1, {\"x\": 2}]}\n" +
+				"}\n",
+			want: []pos{
+				{"sever", 4, 3},
+				{"servers[1].hots", 5, 31},
+			},
+		},
+		{
+			name:  "toml",
+			codec: tomlCodec{},
+			data:  "name = \"x\"\nsurname = \"y\"\n[sever]\nport = 1\nhost = \"h\"\n[server]\nprot = 2\n",
+			want: []pos{
+				{"surname", 2, 1},
+				{"sever", 3, 2},
+				{"server.prot", 7, 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unknown, err := tt.codec.UnknownFields([]byte(tt.data), &strictCfg{})
+			if err != nil {
+				t.Fatalf("UnknownFields: %v", err)
+			}
+			var got []pos
+			for _, u := range unknown {
+				got = append(got, pos{u.Key, u.Line, u.Column})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("unknown = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+type strictEmbedded struct {
+	Level string `json:"level" toml:"level"`
+}
+
+// strictEdgeCfg holds tags whose meaning depends on the decoder's own rules.
+type strictEdgeCfg struct {
+	strictEmbedded
+	Dash    string            `json:"-," yaml:"-" toml:"-"`
+	Skipped string            `json:"-" toml:"-"`
+	Lower   string            `json:",omitempty"`
+	Rest    map[string]string `yaml:",inline" json:"-" toml:"-"`
+	Servers []strictServer    `json:"servers" yaml:"servers" toml:"servers"`
+	Server  strictServer      `json:"server" yaml:"server" toml:"server"`
+}
+
+func TestUnknownFields_DecoderRules(t *testing.T) {
+	type pos struct {
+		Key          string
+		Line, Column int
+	}
+	tests := []struct {
+		name  string
+		codec StrictCodec
+		data  string
+		want  []pos
+	}{
+		{
+			name:  "json: \"-,\" names a field \"-\", embedded fields are promoted, names fold case",
+			codec: jsonCodec{},
+			data:  `{"-": "x", "level": "debug", "LOWER": "y", "Skipped": "z"}`,
+			want:  []pos{{"Skipped", 1, 44}},
+		},
+		{
+			name:  "yaml: inline map accepts any key, \"-\" is skipped",
+			codec: yamlCodec{},
+			data:  "anything: x\nlower: y\nservers:\n  - host: a\n    hots: b\n",
+			want:  []pos{{"servers[0].hots", 5, 5}},
+		},
+		{
+			name:  "toml: dotted, quoted and array of tables keys",
+			codec: tomlCodec{},
+			data: "level = \"debug\"\n" +
+				"server.prot = 1\n" +
+				"\"quo ted\" = 2\n" +
+				"s = \"\"\"\n" +
+				"x = 1\n" +
+				"\"\"\"\n" +
+				"inline = {a = 1}\n" +
+				"[[servers]]\n" +
+				"  host = \"a\"\n" +
+				"  hots = \"b\"\n",
+			want: []pos{
+				{"server.prot", 2, 8},
+				{`"quo ted"`, 3, 1},
+				{"s", 4, 1},
+				{"inline", 7, 1},
+				{"servers.hots", 10, 3},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unknown, err := tt.codec.UnknownFields([]byte(tt.data), &strictEdgeCfg{})
+			if err != nil {
+				t.Fatalf("UnknownFields: %v", err)
+			}
+			var got []pos
+			for _, u := range unknown {
+				got = append(got, pos{u.Key, u.Line, u.Column})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("unknown = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProvider_Get_Strict(t *testing.T) {
+	td := t.TempDir()
+	path := filepath.Join(td, "config.yaml")
+	writeFile(t, path, "name: x\nsever:\n  port: 1\ncont: 2\n")
+	t.Setenv("MYAPP_CONFIG_PATH", path)
+
+	t.Run("unknown keys are ignored by default", func(t *testing.T) {
+		if _, _, _, err := New[testCfg2](WithEnvPrefix[testCfg2]("MYAPP")).Get(); err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+	})
+
+	t.Run("strict mode reports every unknown key", func(t *testing.T) {
+		_, _, _, err := New[testCfg2](WithEnvPrefix[testCfg2]("MYAPP"), WithStrict[testCfg2]()).Get()
+		if !errors.Is(err, ErrUnknownField) {
+			t.Fatalf("error = %v, want ErrUnknownField", err)
+		}
+		var uf *UnknownFieldError
+		if !errors.As(err, &uf) || uf.Path != path || uf.Key != "sever" || uf.Line != 2 || uf.Column != 1 {
+			t.Fatalf("first UnknownFieldError = %+v", uf)
+		}
+		want := "unknown field: " + path + ":2:1: sever\nunknown field: " + path + ":4:1: cont"
+		if err.Error() != want {
+			t.Fatalf("error = %q, want %q", err.Error(), want)
+		}
+	})
+
+	t.Run("strict mode checks every merged file", func(t *testing.T) {
+		layer := filepath.Join(td, "base.json")
+		writeFile(t, layer, `{"nmae": "y"}`)
+		_, _, _, err := New[testCfg2](
+			WithEnvPrefix[testCfg2]("MYAPP"),
+			WithFiles[testCfg2](layer),
+			WithStrict[testCfg2](),
+		).Get()
+		var uf *UnknownFieldError
+		if !errors.As(err, &uf) || uf.Path != layer || uf.Key != "nmae" {
+			t.Fatalf("error = %v, want unknown field nmae in %s", err, layer)
+		}
+	})
+}
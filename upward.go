@@ -0,0 +1,128 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// rootKey is the top-level key that stops the upward search, see
+	// WithUpwardSearch.
+	rootKey = "root"
+	// repoMarker is the entry that marks a repository root.
+	repoMarker = ".git"
+)
+
+// WithUpwardSearch enables project-local config files. Starting in the working
+// directory and walking up to the file system root, the Provider looks for the
+// first of names in each directory (e.g. ".myapprc.yml", "myapp.yml"). The search
+// stops at a directory containing .git, or at a found file with a top-level
+// `root: true` key. The files found are merged over the user config file and its
+// profile overlay, the nearest file last, so that it wins. They are never created
+// or written. A name without an extension, such as ".myapprc", is read as YAML;
+// otherwise its extension selects the codec. Only the files found by the search
+// may use the `root` key as a directive, and only with a boolean value. Panics if
+// names is empty, or a name is empty, contains a path separator or has an
+// extension without a codec.
+func WithUpwardSearch[T any](names ...string) Option[T] {
+	return func(m *Provider[T]) {
+		if len(names) == 0 {
+			panic("config: WithUpwardSearch: names cannot be empty")
+		}
+		for _, name := range names {
+			switch {
+			case name == "":
+				panic("config: WithUpwardSearch: name cannot be empty")
+			case filepath.Base(name) != name:
+				panic("config: WithUpwardSearch: name cannot contain a path separator")
+			}
+		}
+		m.upwardNames = append([]string(nil), names...)
+	}
+}
+
+// projectFiles returns the project config files found by WithUpwardSearch, the
+// farthest first.
+func (m *Provider[T]) projectFiles(fio fileIO) ([]string, error) {
+	if len(m.upwardNames) == 0 {
+		return nil, nil
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("upward search: %w", err)
+	}
+	var files []string
+	for {
+		file, err := fio.findFirst(dir, m.upwardNames)
+		if err != nil {
+			return nil, err
+		}
+		stop := false
+		if file != "" {
+			files = append([]string{file}, files...)
+			if stop, err = fio.isRoot(file); err != nil {
+				return nil, err
+			}
+		}
+		if !stop {
+			stop, err = fio.exists(filepath.Join(dir, repoMarker))
+			if err != nil {
+				return nil, err
+			}
+		}
+		parent := filepath.Dir(dir)
+		if stop || parent == dir {
+			return files, nil
+		}
+		dir = parent
+	}
+}
+
+// findFirst returns the path of the first of names that exists in dir, or "".
+func (f fileIO) findFirst(dir string, names []string) (string, error) {
+	for _, name := range names {
+		p := filepath.Join(dir, name)
+		ok, err := f.exists(p)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return p, nil
+		}
+	}
+	return "", nil
+}
+
+// exists reports whether p exists.
+func (f fileIO) exists(p string) (bool, error) {
+	_, err := f.stat(p)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, fs.ErrNotExist):
+		return false, nil
+	default:
+		return false, fmt.Errorf("read %s: %w", p, err)
+	}
+}
+
+// extensionless reports whether the file name has no extension. A dotfile name
+// such as ".myapprc" has none, although filepath.Ext returns the whole name.
+func extensionless(name string) bool {
+	ext := filepath.Ext(name)
+	return ext == "" || ext == name
+}
+
+// isRoot reports whether the config file at path sets `root: true`.
+func (f fileIO) isRoot(path string) (bool, error) {
+	tree, err := f.readTree(path)
+	if err != nil {
+		return false, err
+	}
+	m, _ := tree.(map[string]interface{})
+	root, _ := m[rootKey].(bool)
+	return root, nil
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// chdir changes the working directory for the duration of the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatalf("chdir back: %v", err)
+		}
+	})
+}
+
+func TestProvider_Get_UpwardSearch(t *testing.T) {
+	td, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("EvalSymlinks: %v", err)
+	}
+	repo := filepath.Join(td, "repo")
+	sub := filepath.Join(repo, "sub")
+	deep := filepath.Join(sub, "deep")
+	user := filepath.Join(td, "user", "config.yml")
+	writeFile(t, filepath.Join(td, "myapp.yml"), "name: outside\n")
+	writeFile(t, filepath.Join(repo, ".git", "HEAD"), "ref: refs/heads/main\n")
+	writeFile(t, filepath.Join(repo, ".myapprc.yml"), "name: repo\nport: 1\n")
+	writeFile(t, filepath.Join(repo, "myapp.yml"), "name: ignored\n")
+	writeFile(t, user, "name: user\nhosts: [u]\n")
+	if err := os.MkdirAll(deep, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	chdir(t, deep)
+	t.Setenv("MYAPP_CONFIG_PATH", user)
+
+	tests := []struct {
+		name      string
+		subFile   string
+		opts      []Option[filesCfg]
+		wantName  string
+		wantPort  int
+		wantFiles []string
+	}{
+		{
+			name:      "stops at .git",
+			subFile:   "port: 2\n",
+			wantName:  "repo",
+			wantPort:  2,
+			wantFiles: []string{user, filepath.Join(repo, ".myapprc.yml"), filepath.Join(sub, ".myapprc.yml")},
+		},
+		{
+			name:      "stops at root: true",
+			subFile:   "root: true\nport: 2\n",
+			opts:      []Option[filesCfg]{WithStrict[filesCfg]()},
+			wantName:  "user",
+			wantPort:  2,
+			wantFiles: []string{user, filepath.Join(sub, ".myapprc.yml")},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			writeFile(t, filepath.Join(sub, ".myapprc.yml"), tc.subFile)
+			opts := append([]Option[filesCfg]{
+				WithEnvPrefix[filesCfg]("MYAPP"),
+				WithUpwardSearch[filesCfg](".myapprc.yml", "myapp.yml"),
+			}, tc.opts...)
+			p := New[filesCfg](opts...)
+			cfg, _, _, err := p.Get()
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if cfg.Name != tc.wantName || cfg.Port != tc.wantPort || !reflect.DeepEqual(cfg.Hosts, []string{"u"}) {
+				t.Fatalf("cfg = %+v, want name %q and port %d over the user file", cfg, tc.wantName, tc.wantPort)
+			}
+			if !reflect.DeepEqual(p.Files(), tc.wantFiles) {
+				t.Fatalf("Files = %v, want %v", p.Files(), tc.wantFiles)
+			}
+			paths, err := p.SearchPaths()
+			if err != nil {
+				t.Fatalf("SearchPaths: %v", err)
+			}
+			if !reflect.DeepEqual(paths, tc.wantFiles) {
+				t.Fatalf("SearchPaths = %v, want %v", paths, tc.wantFiles)
+			}
+		})
+	}
+
+	t.Run("created user file does not contain project values", func(t *testing.T) {
+		t.Setenv("MYAPP_CONFIG_PATH", "")
+		t.Setenv("XDG_CONFIG_HOME", filepath.Join(td, "xdg"))
+		t.Setenv("XDG_CONFIG_DIRS", filepath.Join(td, "sys"))
+		writeFile(t, filepath.Join(sub, ".myapprc.yml"), "port: 2\n")
+
+		p := New[filesCfg](
+			WithEnvPrefix[filesCfg]("MYAPP"),
+			WithPersistence[filesCfg]("myapp"),
+			WithUpwardSearch[filesCfg](".myapprc.yml", "myapp.yml"),
+		)
+		cfg, path, created, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if !created || cfg.Port != 2 {
+			t.Fatalf("created=%v cfg=%+v", created, cfg)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read created file: %v", err)
+		}
+		if strings.Contains(string(data), "port: 2") || strings.Contains(string(data), "repo") {
+			t.Fatalf("created file contains project values: %q", data)
+		}
+	})
+}
+
+func TestProvider_Get_UpwardSearch_RootKey(t *testing.T) {
+	type rootCfg struct {
+		Name string `yaml:"name"`
+		Port int    `yaml:"port"`
+		Root string `yaml:"root"`
+	}
+	td, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("EvalSymlinks: %v", err)
+	}
+	repo := filepath.Join(td, "repo")
+	sub := filepath.Join(repo, "sub")
+	user := filepath.Join(td, "user", "config.yml")
+	writeFile(t, filepath.Join(td, ".myapprc"), "port: 9\n")
+	writeFile(t, filepath.Join(repo, ".myapprc"), "root: true\nname: rc\n")
+	writeFile(t, user, "root: /srv/data\nport: 1\n")
+	if err := os.MkdirAll(sub, 0o700); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	chdir(t, sub)
+	t.Setenv("MYAPP_CONFIG_PATH", user)
+
+	t.Run("extensionless name is YAML and root is a directive there only", func(t *testing.T) {
+		p := New[rootCfg](WithEnvPrefix[rootCfg]("MYAPP"), WithUpwardSearch[rootCfg](".myapprc"), WithStrict[rootCfg]())
+		cfg, _, _, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if cfg.Name != "rc" || cfg.Port != 1 || cfg.Root != "/srv/data" {
+			t.Fatalf("cfg = %+v", cfg)
+		}
+		if want := []string{user, filepath.Join(repo, ".myapprc")}; !reflect.DeepEqual(p.Files(), want) {
+			t.Fatalf("Files = %v, want %v", p.Files(), want)
+		}
+	})
+
+	t.Run("non-boolean root in a project file is a value", func(t *testing.T) {
+		writeFile(t, filepath.Join(sub, ".myapprc"), "root: /srv/project\n")
+		defer os.Remove(filepath.Join(sub, ".myapprc"))
+		p := New[rootCfg](WithEnvPrefix[rootCfg]("MYAPP"), WithUpwardSearch[rootCfg](".myapprc"))
+		cfg, _, _, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if cfg.Root != "/srv/project" || cfg.Name != "rc" {
+			t.Fatalf("cfg = %+v", cfg)
+		}
+	})
+
+	t.Run("strict reports root outside project files", func(t *testing.T) {
+		p := New[filesCfg](WithEnvPrefix[filesCfg]("MYAPP"), WithUpwardSearch[filesCfg](".myapprc"), WithStrict[filesCfg]())
+		_, _, _, err := p.Get()
+		var u *UnknownFieldError
+		if !errors.As(err, &u) || u.Path != user || u.Key != "root" {
+			t.Fatalf("error = %v, want root in %s reported", err, user)
+		}
+	})
+}
@@ -1,17 +1,13 @@
 package config
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"reflect"
+	"sort"
 	"strconv"
 	"strings"
-	"time"
-
-	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -40,130 +36,35 @@ func EnsurePath(p string) error {
 }
 
 func loadFromFile(path string, cfg interface{}) error {
+	return fileIO{codecs: registered()}.loadFile(path, cfg)
+}
+
+// loadFile decodes the file at path into cfg with the codec for its extension.
+func (f fileIO) loadFile(path string, cfg interface{}) error {
 	if path == "" {
 		return nil
 	}
-	ext := filepath.Ext(path)
-	if ext != ".yaml" && ext != ".yml" && ext != ".json" {
-		return fmt.Errorf("%w: %s", ErrUnsupportedConfigFileType, ext)
-	}
-	data, err := os.ReadFile(path)
+	codec, data, err := f.read(path)
 	if err != nil {
-		return fmt.Errorf("read %s: %w", path, err)
+		return err
 	}
-	switch ext {
-	case ".json":
-		err = json.Unmarshal(data, cfg)
-	default:
-		err = yaml.Unmarshal(data, cfg)
-	}
-	if err != nil {
-		return fmt.Errorf("%w %s: %w", ErrParse, path, err)
+	if err := codec.Unmarshal(data, cfg); err != nil {
+		return newParseError(path, data, err)
 	}
 	return nil
 }
 
-func applyEnv(v reflect.Value, prefix string, segments []string) {
-	if v.Kind() == reflect.Pointer {
-		if v.IsNil() {
-			return
-		}
-		v = v.Elem()
-	}
-	if v.Kind() != reflect.Struct {
-		return
+// read returns the codec for the file at path and its contents.
+func (f fileIO) read(path string) (Codec, []byte, error) {
+	codec, err := f.codec(path)
+	if err != nil {
+		return nil, nil, err
 	}
-	t := v.Type()
-	for i := 0; i < v.NumField(); i++ {
-		sf := t.Field(i)
-		if sf.PkgPath != "" {
-			continue
-		}
-		tag := sf.Tag.Get(envVarTagName)
-		if tag == "-" {
-			continue
-		}
-		seg := tag
-		if seg == "" {
-			seg = toScreamingSnake(sf.Name)
-		}
-		field := v.Field(i)
-		envName := buildEnvName(prefix, append(segments, seg))
-		switch field.Kind() {
-		case reflect.Struct:
-			applyEnv(field, prefix, append(segments, seg))
-		case reflect.String:
-			if s, ok := getString(envName); ok && field.CanSet() {
-				field.SetString(s)
-			}
-		case reflect.Bool:
-			if b, ok := getBool(envName); ok && field.CanSet() {
-				field.SetBool(b)
-			}
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			if field.Type() == reflect.TypeOf(time.Duration(0)) {
-				if d, ok := getDuration(envName); ok && field.CanSet() {
-					field.SetInt(int64(d))
-				}
-			} else if n, ok := getInt(envName); ok && field.CanSet() {
-				field.SetInt(n)
-			}
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			if n, ok := getInt(envName); ok && field.CanSet() && n >= 0 {
-				field.SetUint(uint64(n))
-			}
-		case reflect.Pointer:
-			elem := field.Type().Elem()
-			switch elem.Kind() {
-			case reflect.Struct:
-				// Allocate *struct only if there is at least one nested env var present
-				// for this segment (e.g., APP_PINNER_*). This avoids allocating when no
-				// relevant env vars are set.
-				base := buildEnvName(prefix, append(segments, seg)) + "_"
-				if hasAnyEnvWithPrefix(base) {
-					if field.IsNil() && field.CanSet() {
-						field.Set(reflect.New(elem))
-					}
-					applyEnv(field, prefix, append(segments, seg))
-				}
-			case reflect.String:
-				if s, ok := getString(envName); ok && field.CanSet() {
-					if field.IsNil() {
-						field.Set(reflect.New(elem))
-					}
-					field.Elem().SetString(s)
-				}
-			case reflect.Bool:
-				if b, ok := getBool(envName); ok && field.CanSet() {
-					if field.IsNil() {
-						field.Set(reflect.New(elem))
-					}
-					field.Elem().SetBool(b)
-				}
-			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-				if elem == reflect.TypeOf(time.Duration(0)) {
-					if d, ok := getDuration(envName); ok && field.CanSet() {
-						if field.IsNil() {
-							field.Set(reflect.New(elem))
-						}
-						field.Elem().SetInt(int64(d))
-					}
-				} else if n, ok := getInt(envName); ok && field.CanSet() {
-					if field.IsNil() {
-						field.Set(reflect.New(elem))
-					}
-					field.Elem().SetInt(n)
-				}
-			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-				if n, ok := getInt(envName); ok && field.CanSet() && n >= 0 {
-					if field.IsNil() {
-						field.Set(reflect.New(elem))
-					}
-					field.Elem().SetUint(uint64(n))
-				}
-			}
-		}
+	data, err := f.readFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read %s: %w", path, err)
 	}
+	return codec, data, nil
 }
 
 func buildEnvName(prefix string, segments []string) string {
@@ -179,56 +80,82 @@ func buildEnvName(prefix string, segments []string) string {
 	}
 }
 
-func getString(name string) (string, bool) {
-	v, ok := os.LookupEnv(name)
-	return v, ok
+// envVars is the environment seen by environment overrides. Variables are looked
+// up with lookup, falling back to vars; only vars can be listed, e.g. to find map
+// keys and slice indices.
+type envVars struct {
+	lookup func(string) (string, bool) // nil: vars only
+	vars   map[string]string
 }
 
-func getInt(name string) (int64, bool) {
-	v, ok := os.LookupEnv(name)
-	if !ok {
-		return 0, false
-	}
-	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
-	if err != nil {
-		return 0, false
-	}
-	return n, true
+// processEnv returns the variables of the process environment.
+func processEnv() envVars {
+	return envVars{vars: parseEnviron(os.Environ())}
 }
 
-func getBool(name string) (bool, bool) {
-	v, ok := os.LookupEnv(name)
-	if !ok {
-		return false, false
+// parseEnviron converts "NAME=value" entries, as returned by os.Environ, to a map.
+func parseEnviron(environ []string) map[string]string {
+	vars := make(map[string]string, len(environ))
+	for _, e := range environ {
+		name, value, _ := strings.Cut(e, "=")
+		vars[name] = value
 	}
-	b, err := strconv.ParseBool(strings.TrimSpace(v))
-	if err != nil {
-		return false, false
-	}
-	return b, true
+	return vars
 }
 
-func getDuration(name string) (time.Duration, bool) {
-	v, ok := os.LookupEnv(name)
-	if !ok {
-		return 0, false
-	}
-	d, err := time.ParseDuration(strings.TrimSpace(v))
-	if err != nil {
-		return 0, false
+func (e envVars) getString(name string) (string, bool) {
+	if e.lookup != nil {
+		if v, ok := e.lookup(name); ok {
+			return v, true
+		}
 	}
-	return d, true
+	v, ok := e.vars[name]
+	return v, ok
 }
 
-func hasAnyEnvWithPrefix(prefix string) bool {
-	for _, e := range os.Environ() {
-		if strings.HasPrefix(e, prefix) {
+func (e envVars) hasAnyWithPrefix(prefix string) bool {
+	for name := range e.vars {
+		if strings.HasPrefix(name, prefix) {
 			return true
 		}
 	}
 	return false
 }
 
+// keys returns the sorted name suffixes of environment variables named
+// <prefix><suffix> with a non-empty suffix (e.g. "TEAM" for APP_LABELS_TEAM).
+func (e envVars) keys(prefix string) []string {
+	var keys []string
+	for name := range e.vars {
+		if rest, ok := strings.CutPrefix(name, prefix); ok && rest != "" {
+			keys = append(keys, rest)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// indices returns the sorted, distinct indices i of environment variables named
+// <prefix><i>_..., as used for slices of structs (e.g. APP_SERVERS_0_HOST).
+func (e envVars) indices(prefix string) []int {
+	seen := map[int]bool{}
+	var indices []int
+	for _, key := range e.keys(prefix) {
+		digits, _, ok := strings.Cut(key, "_")
+		if !ok || digits == "" || strings.Trim(digits, "0123456789") != "" {
+			continue
+		}
+		i, err := strconv.Atoi(digits)
+		if err != nil || seen[i] {
+			continue
+		}
+		seen[i] = true
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	return indices
+}
+
 func toScreamingSnake(s string) string {
 	var b strings.Builder
 	for i, r := range s {
@@ -253,7 +180,13 @@ func toUpper(r rune) rune {
 	return r
 }
 
-func writeToFile(path string, cfg interface{}) (retErr error) {
+func writeToFile(path string, cfg interface{}) error {
+	return fileIO{codecs: registered()}.writeFile(path, cfg)
+}
+
+// writeFile atomically writes cfg to path with the codec for its extension.
+// A path without an extension is written as YAML.
+func (f fileIO) writeFile(path string, cfg interface{}) (retErr error) {
 	// Guard against panics from encoders (e.g., yaml on unsupported kinds like func).
 	defer func() {
 		if r := recover(); r != nil {
@@ -264,20 +197,20 @@ func writeToFile(path string, cfg interface{}) (retErr error) {
 	}()
 
 	ext := filepath.Ext(path)
-	if ext != "" && ext != ".yaml" && ext != ".yml" && ext != ".json" {
-		return fmt.Errorf("%w: %s", ErrUnsupportedConfigFileType, ext)
+	codec, err := f.codecs.lookup(ext)
+	if ext == "" {
+		codec, err = yamlCodec{}, nil
 	}
-	var data []byte
-	var err error
-	switch ext {
-	case ".json":
-		data, err = json.MarshalIndent(cfg, "", "  ")
-	default:
-		data, err = yaml.Marshal(cfg)
+	if err != nil {
+		return err
 	}
+	data, err := codec.Marshal(cfg)
 	if err != nil {
 		return fmt.Errorf("%w as %s: %w", ErrFormat, ext, err)
 	}
+	if f.fsys != nil {
+		return f.writeFS(path, data)
+	}
 	dir := filepath.Dir(path)
 	tmpFile, err := os.CreateTemp(dir, "temp-config-*"+ext)
 	if err != nil {
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultWatchInterval = time.Second
+	defaultWatchDebounce = 100 * time.Millisecond
+)
+
+// WithOnChange registers a callback invoked after Reload (called directly or by
+// Watch) publishes a new configuration. old is the previously published value and
+// may be nil if the first initialization failed. Callbacks run synchronously and
+// in registration order; they must not call Reload. Panics if fn is nil.
+func WithOnChange[T any](fn func(old, new *T)) Option[T] {
+	return func(m *Provider[T]) {
+		if fn == nil {
+			panic("config: WithOnChange: fn cannot be nil")
+		}
+		m.onChange = append(m.onChange, fn)
+	}
+}
+
+// WithWatchInterval sets how often Watch polls the config file. Defaults to 1s.
+// Panics if d is not positive.
+func WithWatchInterval[T any](d time.Duration) Option[T] {
+	return func(m *Provider[T]) {
+		if d <= 0 {
+			panic("config: WithWatchInterval: interval must be positive")
+		}
+		m.watchInterval = d
+	}
+}
+
+// WithWatchDebounce sets how long the config file must stay unchanged after a
+// detected change before Watch reloads it. This collapses bursts of writes (e.g.
+// an editor saving several times) into a single reload. Defaults to 100ms; zero
+// reloads on the first poll that observes a change. Panics if d is negative.
+func WithWatchDebounce[T any](d time.Duration) Option[T] {
+	return func(m *Provider[T]) {
+		if d < 0 {
+			panic("config: WithWatchDebounce: debounce cannot be negative")
+		}
+		m.watchDebounce = d
+	}
+}
+
+// Watch monitors the resolved config file and its profile overlay, the files added
+// with WithFiles, the directories added with WithConfigDir and the loaded project
+// and included files, and calls Reload when one of them is created, modified,
+// replaced (e.g. written to a temp file and renamed over) or deleted.
+// It uses stat polling, so it works on any filesystem without OS notification
+// support. Reload errors do not stop watching: they are reported to ErrOut of the
+// configured streams and the previous configuration stays in effect.
+//
+// Watch initializes the Provider if needed and blocks until ctx is done, returning
+// ctx.Err(). It returns ErrNoConfigPath if there is no config file to watch.
+func (m *Provider[T]) Watch(ctx context.Context) error {
+	path, err := m.resolveConfigPath()
+	if err != nil {
+		return err
+	}
+	if path == "" && len(m.layerFiles) == 0 && len(m.configDirs) == 0 && len(m.upwardNames) == 0 {
+		return ErrNoConfigPath
+	}
+
+	// An initialization error is not fatal here: a later change to the file
+	// may fix it, and a successful Reload clears it.
+	m.ensureInit(ctx)
+
+	ticker := time.NewTicker(m.watchInterval)
+	defer ticker.Stop()
+
+	fio := m.fileIO()
+	prev := fio.statFiles(m.watchPaths(fio, path))
+	pending := false
+	var lastChange time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case now := <-ticker.C:
+			if cur := fio.statFiles(m.watchPaths(fio, path)); changedAny(cur, prev) {
+				prev = cur
+				pending = true
+				lastChange = now
+			}
+			if !pending || now.Sub(lastChange) < m.watchDebounce {
+				continue
+			}
+			pending = false
+			// Stat before reloading so that a change racing with the reload
+			// is picked up by the next poll.
+			paths := m.watchPaths(fio, path)
+			prev = fio.statFiles(paths)
+			if err := m.Reload(ctx); err != nil && ctx.Err() == nil {
+				if m.streams != nil && m.streams.ErrOut() != nil {
+					fmt.Fprintf(m.streams.ErrOut(), "config: warning: reload of %s failed: %v\n", strings.Join(paths, ", "), err)
+				}
+			}
+		}
+	}
+}
+
+// watchPaths returns the files Watch polls: the config file layers, including the
+// current contents of the config directories, the profile overlay and the files
+// loaded last time, which include the files pulled in by include directives.
+func (m *Provider[T]) watchPaths(fio fileIO, path string) []string {
+	layers := m.fileLayers()
+	paths, err := fio.layerPaths(layers, m.configDirs, path)
+	if err != nil {
+		paths = filePaths(layers, path)
+	}
+	// An invalid profile name fails the reload, which reports it.
+	if profile, err := m.profileName(); err == nil {
+		paths = append(paths, profilePath(path, profile))
+	}
+	m.mu.RLock()
+	loaded := m.loadedFiles
+	m.mu.RUnlock()
+	return filePaths(append(paths, loaded...), "")
+}
+
+// fileState is a snapshot of the file metadata Watch compares between polls.
+type fileState struct {
+	info os.FileInfo // nil if the file does not exist or cannot be stat'ed
+	os   bool        // info is from the OS file system and identifies the file
+}
+
+func (f fileIO) statFiles(paths []string) map[string]fileState {
+	states := make(map[string]fileState, len(paths))
+	for _, p := range paths {
+		states[p] = f.statFile(p)
+	}
+	return states
+}
+
+func (f fileIO) statFile(path string) fileState {
+	info, err := f.stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{info: info, os: f.fsys == nil}
+}
+
+// changed reports whether the file appeared, disappeared, was replaced by another
+// file (a different inode, as after a rename) or had its size or mtime updated.
+func (s fileState) changed(prev fileState) bool {
+	switch {
+	case s.info == nil || prev.info == nil:
+		return (s.info == nil) != (prev.info == nil)
+	case s.os && !os.SameFile(s.info, prev.info):
+		return true
+	default:
+		return s.info.Size() != prev.info.Size() || !s.info.ModTime().Equal(prev.info.ModTime())
+	}
+}
+
+// changedAny reports whether any of the files changed between two polls, or the
+// set of watched files did.
+func changedAny(cur, prev map[string]fileState) bool {
+	if len(cur) != len(prev) {
+		return true
+	}
+	for p, s := range cur {
+		old, ok := prev[p]
+		if !ok || s.changed(old) {
+			return true
+		}
+	}
+	return false
+}
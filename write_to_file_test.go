@@ -10,8 +10,8 @@ import (
 
 // Simple serializable type for success cases
 type sampleCfg struct {
-	Name  string `json:"name" yaml:"name"`
-	Count int    `json:"count" yaml:"count"`
+	Name  string `json:"name" yaml:"name" toml:"name"`
+	Count int    `json:"count" yaml:"count" toml:"count"`
 }
 
 // Types that will fail marshaling
@@ -23,6 +23,10 @@ type jsonBad struct {
 	F func() // JSON marshaller errors on functions (unsupported type)
 }
 
+type tomlBad struct {
+	F func() `toml:"f"` // TOML marshaller errors on functions (unsupported type)
+}
+
 func TestWriteToFile(t *testing.T) {
 	td := t.TempDir()
 
@@ -64,6 +68,20 @@ func TestWriteToFile(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "success: toml extension",
+			path: func() string { return filepath.Join(td, "ok.toml") },
+			cfg:  &sampleCfg{Name: "dave", Count: 4},
+			verify: func(t *testing.T, p string) {
+				var got sampleCfg
+				if err := loadFromFile(p, &got); err != nil {
+					t.Fatalf("read back: %v", err)
+				}
+				if got != (sampleCfg{Name: "dave", Count: 4}) {
+					t.Fatalf("toml round trip mismatch: %+v", got)
+				}
+			},
+		},
 		{
 			name: "success: no extension -> yaml by default",
 			path: func() string { return filepath.Join(td, "config") }, // no extension
@@ -97,6 +115,12 @@ func TestWriteToFile(t *testing.T) {
 			cfg:       &jsonBad{F: func() {}},
 			wantErrIs: ErrFormat,
 		},
+		{
+			name:      "marshal error: toml",
+			path:      func() string { return filepath.Join(td, "bad.toml") },
+			cfg:       &tomlBad{F: func() {}},
+			wantErrIs: ErrFormat,
+		},
 		{
 			name: "create temp file error: parent dir does not exist",
 			path: func() string {
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultXDGConfigDirs is the XDG_CONFIG_DIRS value used when it is unset.
+const defaultXDGConfigDirs = "/etc/xdg"
+
+// SearchPaths returns the locations the Provider reads configuration from, in
+// merge order, so that later entries take precedence: the system-wide files from
+// XDG_CONFIG_DIRS, the WithFiles layers, the WithConfigDir directories, the
+// resolved config file, its WithProfile overlay and the project files found by
+// WithUpwardSearch. Paths other than the project files are returned whether or
+// not they exist, e.g. to list them in a --help text. The config file is always
+// created at the resolved path, never in a system directory, and without the
+// keys the system-wide files set.
+func (m *Provider[T]) SearchPaths() ([]string, error) {
+	path, err := m.resolveConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	project, err := m.projectFiles(m.fileIO())
+	if err != nil {
+		return nil, err
+	}
+	profile, err := m.profileName()
+	if err != nil {
+		return nil, err
+	}
+	paths := append(m.fileLayers(), m.configDirs...)
+	paths = append(paths, path, profilePath(path, profile))
+	return filePaths(append(paths, project...), ""), nil
+}
+
+// fileLayers returns the config files merged before the config directories and
+// the resolved config file: the system-wide files, then the WithFiles layers.
+func (m *Provider[T]) fileLayers() []string {
+	return append(m.systemConfigPaths(), m.layerFiles...)
+}
+
+// systemConfigPaths returns the config files below the XDG_CONFIG_DIRS base
+// directories, least important first. They are only searched for the persistence
+// layout, not for a path set explicitly (see WithConfigPath).
+func (m *Provider[T]) systemConfigPaths() []string {
+	if m.dirName == "" || m.explicitConfigPath() != "" {
+		return nil
+	}
+	dirs := m.getenv("XDG_CONFIG_DIRS")
+	if dirs == "" {
+		dirs = defaultXDGConfigDirs
+	}
+	var paths []string
+	for _, dir := range strings.Split(dirs, string(os.PathListSeparator)) {
+		// The spec requires absolute paths; others are ignored.
+		if !filepath.IsAbs(dir) {
+			continue
+		}
+		paths = append([]string{filepath.Join(dir, m.dirName, m.fileName)}, paths...)
+	}
+	return paths
+}
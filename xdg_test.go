@@ -0,0 +1,158 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProvider_SearchPaths(t *testing.T) {
+	td := t.TempDir()
+	home := filepath.Join(td, "home")
+	sys1 := filepath.Join(td, "sys1")
+	sys2 := filepath.Join(td, "sys2")
+	t.Setenv("XDG_CONFIG_HOME", home)
+	sep := string(os.PathListSeparator)
+
+	tests := []struct {
+		name       string
+		dirs       string
+		configPath string
+		opts       []Option[filesCfg]
+		want       []string
+	}{
+		{
+			name: "XDG_CONFIG_DIRS in reverse order of preference",
+			dirs: sys1 + sep + "relative" + sep + sep + sys2,
+			opts: []Option[filesCfg]{WithPersistence[filesCfg]("myapp")},
+			want: []string{
+				filepath.Join(sys2, "myapp", "config.yml"),
+				filepath.Join(sys1, "myapp", "config.yml"),
+				filepath.Join(home, "myapp", "config.yml"),
+			},
+		},
+		{
+			name: "default XDG_CONFIG_DIRS",
+			opts: []Option[filesCfg]{WithPersistence[filesCfg]("myapp"), WithFileName[filesCfg]("app.json")},
+			want: []string{
+				filepath.Join(filepath.FromSlash(defaultXDGConfigDirs), "myapp", "app.json"),
+				filepath.Join(home, "myapp", "app.json"),
+			},
+		},
+		{
+			name: "all layers",
+			dirs: sys1,
+			opts: []Option[filesCfg]{
+				WithPersistence[filesCfg]("myapp"),
+				WithFiles[filesCfg]("base.yml"),
+				WithConfigDir[filesCfg]("conf.d"),
+				WithProfile[filesCfg]("dev"),
+			},
+			want: []string{
+				filepath.Join(sys1, "myapp", "config.yml"),
+				"base.yml",
+				"conf.d",
+				filepath.Join(home, "myapp", "config.yml"),
+				filepath.Join(home, "myapp", "config.dev.yml"),
+			},
+		},
+		{
+			name:       "config path from env skips system dirs",
+			dirs:       sys1,
+			configPath: "/srv/app.yml",
+			opts:       []Option[filesCfg]{WithPersistence[filesCfg]("myapp")},
+			want:       []string{"/srv/app.yml"},
+		},
+		{
+			name: "non-persistent",
+			dirs: sys1,
+			want: nil,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_DIRS", tc.dirs)
+			t.Setenv("MYAPP_CONFIG_PATH", tc.configPath)
+			p := New[filesCfg](append([]Option[filesCfg]{WithEnvPrefix[filesCfg]("MYAPP")}, tc.opts...)...)
+			got, err := p.SearchPaths()
+			if err != nil {
+				t.Fatalf("SearchPaths: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("SearchPaths = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProvider_Get_XDGConfigDirs(t *testing.T) {
+	td := t.TempDir()
+	home := filepath.Join(td, "home")
+	sys1 := filepath.Join(td, "sys1")
+	sys2 := filepath.Join(td, "sys2")
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("XDG_CONFIG_DIRS", sys1+string(os.PathListSeparator)+sys2)
+	writeFile(t, filepath.Join(sys2, "myapp", "config.yml"), "name: sys2\nport: 2\nlabels:\n  a: sys2\n")
+	writeFile(t, filepath.Join(sys1, "myapp", "config.yml"), "port: 1\n")
+
+	t.Run("user file is created over system defaults", func(t *testing.T) {
+		p := New[filesCfg](WithPersistence[filesCfg]("myapp"))
+		cfg, path, created, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if !created || path != filepath.Join(home, "myapp", "config.yml") {
+			t.Fatalf("created=%v path=%q, want the user file to be created", created, path)
+		}
+		if cfg.Name != "sys2" || cfg.Port != 1 {
+			t.Fatalf("system files not merged in order: %+v", cfg)
+		}
+		if _, err := os.Stat(filepath.Join(sys1, "myapp", "config.yml")); err != nil {
+			t.Fatalf("system file touched: %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read created file: %v", err)
+		}
+		for _, key := range []string{"name:", "port:", "labels:"} {
+			if strings.Contains(string(data), key) {
+				t.Fatalf("created file contains %q from the system files: %q", key, data)
+			}
+		}
+
+		// Later admin edits of the system files are not shadowed by the user file.
+		writeFile(t, filepath.Join(sys1, "myapp", "config.yml"), "port: 3\n")
+		if err := p.Reload(context.Background()); err != nil {
+			t.Fatalf("Reload: %v", err)
+		}
+		if cfg, _, _, _ = p.Get(); cfg.Name != "sys2" || cfg.Port != 3 {
+			t.Fatalf("cfg after editing a system file = %+v", cfg)
+		}
+	})
+
+	t.Run("user file takes precedence", func(t *testing.T) {
+		writeFile(t, filepath.Join(home, "myapp", "config.yml"), "port: 8080\n")
+		p := New[filesCfg](WithPersistence[filesCfg]("myapp"))
+		cfg, _, created, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if created || cfg.Name != "sys2" || cfg.Port != 8080 || cfg.Labels["a"] != "sys2" {
+			t.Fatalf("created=%v cfg=%+v", created, cfg)
+		}
+		want := []string{
+			filepath.Join(sys2, "myapp", "config.yml"),
+			filepath.Join(sys1, "myapp", "config.yml"),
+			filepath.Join(home, "myapp", "config.yml"),
+		}
+		if !reflect.DeepEqual(p.Files(), want) {
+			t.Fatalf("Files = %v, want %v", p.Files(), want)
+		}
+		if data, _ := os.ReadFile(filepath.Join(sys2, "myapp", "config.yml")); !strings.HasPrefix(string(data), "name: sys2") {
+			t.Fatalf("system file modified: %q", data)
+		}
+	})
+}